@@ -0,0 +1,167 @@
+package auctions
+
+import (
+	"log"
+	"sync"
+
+	"github.com/baldator/vega-bot/state"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+// Tracker keeps the active auctions of every market. Its state is saved in
+// the state store so auctions survive restarts. It is safe for concurrent use
+type Tracker struct {
+	store  state.StateStore
+	mu     sync.Mutex
+	active map[string]state.Auction
+}
+
+// NewTracker creates a tracker loaded with the auctions saved in the store
+func NewTracker(store state.StateStore) (*Tracker, error) {
+	auctions, err := store.Auctions()
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := &Tracker{
+		store:  store,
+		active: make(map[string]state.Auction),
+	}
+	for _, auction := range auctions {
+		tracker.active[auction.MarketID] = auction
+	}
+	return tracker, nil
+}
+
+// Reconcile aligns the tracked auctions with the current trading mode of the
+// markets, for auctions that started or ended while the bot was not running
+func (tracker *Tracker) Reconcile(ctx context.Context, dataClient api.TradingDataServiceClient) error {
+	response, err := dataClient.MarketsData(ctx, &api.MarketsDataRequest{})
+	if err != nil {
+		return errors.Wrap(err, "could not get markets data")
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	inAuction := make(map[string]bool)
+	for _, data := range response.MarketsData {
+		if !isAuction(data.MarketTradingMode) {
+			continue
+		}
+		inAuction[data.Market] = true
+
+		auction := state.Auction{
+			MarketID: data.Market,
+			Trigger:  data.Trigger,
+			Start:    data.AuctionStart,
+			End:      data.AuctionEnd,
+		}
+		if _, ok := tracker.active[data.Market]; !ok {
+			log.Printf("Auction on market %s started while the bot was stopped\n", data.Market)
+		}
+		err = tracker.save(auction)
+		if err != nil {
+			return err
+		}
+	}
+
+	for marketID := range tracker.active {
+		if inAuction[marketID] {
+			continue
+		}
+		log.Printf("Auction on market %s ended while the bot was stopped\n", marketID)
+		err = tracker.delete(marketID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Auction returns the tracked auction of a market. ok is false when the
+// market is not in auction
+func (tracker *Tracker) Auction(marketID string) (auction state.Auction, ok bool) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	auction, ok = tracker.active[marketID]
+	return auction, ok
+}
+
+// Start records an auction start. extended is true when the market was
+// already in auction
+func (tracker *Tracker) Start(event *proto.AuctionEvent) (extended bool, err error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	auction, extended := tracker.active[event.MarketId]
+	if !extended {
+		auction = state.Auction{
+			MarketID: event.MarketId,
+			Trigger:  event.Trigger,
+			Start:    event.Start,
+		}
+	}
+	auction.End = event.End
+
+	return extended, tracker.save(auction)
+}
+
+// End records an auction end and returns the auction. ok is false when the
+// auction was not being tracked
+func (tracker *Tracker) End(event *proto.AuctionEvent) (auction state.Auction, ok bool, err error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	auction, ok = tracker.active[event.MarketId]
+	if !ok {
+		return auction, false, nil
+	}
+	return auction, true, tracker.delete(event.MarketId)
+}
+
+// Active returns the tracked auctions
+func (tracker *Tracker) Active() []state.Auction {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	auctions := make([]state.Auction, 0, len(tracker.active))
+	for _, auction := range tracker.active {
+		auctions = append(auctions, auction)
+	}
+	return auctions
+}
+
+// save stores an auction. The lock must be held
+func (tracker *Tracker) save(auction state.Auction) error {
+	err := tracker.store.SetAuction(auction)
+	if err != nil {
+		return err
+	}
+	tracker.active[auction.MarketID] = auction
+	return nil
+}
+
+// delete forgets an auction. The lock must be held
+func (tracker *Tracker) delete(marketID string) error {
+	err := tracker.store.DeleteAuction(marketID)
+	if err != nil {
+		return err
+	}
+	delete(tracker.active, marketID)
+	return nil
+}
+
+func isAuction(mode proto.Market_TradingMode) bool {
+	switch mode {
+	case proto.Market_TRADING_MODE_BATCH_AUCTION, proto.Market_TRADING_MODE_OPENING_AUCTION, proto.Market_TRADING_MODE_MONITORING_AUCTION:
+		return true
+	}
+	return false
+}
@@ -1,32 +1,86 @@
 package main
 
-import "github.com/ilyakaznacheev/cleanenv"
+import (
+	"errors"
+	"time"
+
+	"github.com/baldator/vega-bot/eventhandlers"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
 
 type ConfigVars struct {
-	SocialServiceURL             string  `yaml:"SocialServiceURL" env:"SOCIALSERVICEURL" env-default:"127.0.0.1"`
-	SocialTwitterEnabled         bool    `yaml:"SocialTwitterEnabled" env:"TWITTER-ENABLED" env-default:"false"`
-	SocialTelegramEnabled        bool    `yaml:"SocialTelegramEnabled" env:"TELEGRAM-ENABLED" env-default:"false"`
-	SocialDiscordEnabled         bool    `yaml:"SocialDiscordEnabled" env:"DISCORD-ENABLED" env-default:"false"`
-	SocialSlackEnabled           bool    `yaml:"SocialSlackEnabled" env:"SLACK-ENABLE" env-default:"false"`
-	SocialServiceKey             string  `yaml:"SocialServiceKey" env:"SOCIALSERVICEKEY" env-default:""`
-	SocialServiceSecret          string  `yaml:"SocialServiceSecret" env:"SocialServiceSecret" env-default:""`
-	GrpcNodeURL                  string  `yaml:"GrpcNodeUrl" env:"GRPCNODEURL" env-default:"n06.testnet.vega.xyz:3002"`
-	WhaleThreshold               float64 `yaml:"WhaleThreshold" env:"WHALETHRESHOLD" env-default:"0.05"`
-	WhaleOrdersThreshold         int     `yaml:"WhaleOrdersThreshold" env:"WHALEORDERSTHRESHOLD" env-default:"100"`
-	SentryEnabled                bool    `yaml:"SentryEnabled" env:"SENTRY-ENABLED" env-default:"false"`
-	SentryDsn                    string  `yaml:"SentryDsn" env:"SENTRY-DSN" env-default:""`
-	PrometheusEnabled            bool    `yaml:"PrometheusEnabled" env:"PROMETHEUS-ENABLED" env-default:"false"`
-	PrometheusPort               int     `yaml:"PrometheusPort" env:"PROMETHEUS-PORT" env-default:"2112"`
-	VegaEventsBatchSize          int64   `yaml:"VegaEventsBatchSize" env:"BATCH-SIZE" env-default:"5000"`
-	VegaOrdersEnabled            bool    `yaml:"VegaOrdersEnabled" env:"ORDERS-ENABLE" env-default:"false"`
-	VegaTradesEnabled            bool    `yaml:"VegaTradesEnabled" env:"TRADES-ENABLE" env-default:"false"`
-	VegaProposalsEnabled         bool    `yaml:"VegaProposalsEnabled" env:"PROPOSALS-ENABLE" env-default:"false"`
-	VegaAuctionsEnabled          bool    `yaml:"VegaAuctionsEnabled" env:"AUCTION-ENABLE" env-default:"false"`
-	VegaAuctionsExtendEnabled    bool    `yaml:"VegaAuctionsExtendEnabled" env:"AUCTION-EXTEND-ENABLE" env-default:"false"`
-	VegaLossSocializationEnabled bool    `yaml:"VegaLossSocializationEnabled" env:"LOSS-SOCIALIZATION-ENABLE" env-default:"false"`
-	VegaNetworkParametersEnabled bool    `yaml:"VegaNetworkParametersEnabled" env:"NETWORK-PARAMETERS-ENABLE" env-default:"false"`
-	BotBlacklistEnabled          bool    `yaml:"BotBlacklistEnabled" env:"BOT-BLACKLIST-ENABLE" env-default:"false"`
-	Debug                        bool    `yaml:"Debug" env:"DEBUG" env-default:"false"`
+	SocialServiceURL             string             `yaml:"SocialServiceURL" env:"SOCIALSERVICEURL" env-default:"127.0.0.1"`
+	SocialTwitterEnabled         bool               `yaml:"SocialTwitterEnabled" env:"TWITTER-ENABLED" env-default:"false"`
+	SocialTelegramEnabled        bool               `yaml:"SocialTelegramEnabled" env:"TELEGRAM-ENABLED" env-default:"false"`
+	SocialDiscordEnabled         bool               `yaml:"SocialDiscordEnabled" env:"DISCORD-ENABLED" env-default:"false"`
+	SocialSlackEnabled           bool               `yaml:"SocialSlackEnabled" env:"SLACK-ENABLE" env-default:"false"`
+	SocialServiceKey             string             `yaml:"SocialServiceKey" env:"SOCIALSERVICEKEY" env-default:""`
+	SocialServiceSecret          string             `yaml:"SocialServiceSecret" env:"SocialServiceSecret" env-default:""`
+	SocialDiscordWebhookURL      string             `yaml:"SocialDiscordWebhookUrl" env:"DISCORD-WEBHOOK-URL" env-default:""`
+	SocialSlackWebhookURL        string             `yaml:"SocialSlackWebhookUrl" env:"SLACK-WEBHOOK-URL" env-default:""`
+	SocialTelegramBotToken       string             `yaml:"SocialTelegramBotToken" env:"TELEGRAM-BOT-TOKEN" env-default:""`
+	SocialTelegramChatID         string             `yaml:"SocialTelegramChatId" env:"TELEGRAM-CHAT-ID" env-default:""`
+	OutboxMaxAttempts            int                `yaml:"OutboxMaxAttempts" env:"OUTBOX-MAX-ATTEMPTS" env-default:"10"`
+	OutboxRetryDelay             time.Duration      `yaml:"OutboxRetryDelay" env:"OUTBOX-RETRY-DELAY" env-default:"5s"`
+	SocialRateLimits             map[string]float64 `yaml:"SocialRateLimits" env:"SOCIAL-RATE-LIMITS"`
+	SocialDedupTTL               time.Duration      `yaml:"SocialDedupTTL" env:"SOCIAL-DEDUP-TTL" env-default:"24h"`
+	SocialRateBurst              int                `yaml:"SocialRateBurst" env:"SOCIAL-RATE-BURST" env-default:"3"`
+	TemplatesDir                 string             `yaml:"TemplatesDir" env:"TEMPLATES-DIR" env-default:""`
+	ExplorerURL                  string             `yaml:"ExplorerUrl" env:"EXPLORER-URL" env-default:"https://explorer.vega.xyz"`
+	GovernanceURL                string             `yaml:"GovernanceUrl" env:"GOVERNANCE-URL" env-default:"https://token.vega.xyz/governance"`
+	GrpcNodeURL                  string             `yaml:"GrpcNodeUrl" env:"GRPCNODEURL" env-default:"n06.testnet.vega.xyz:3002"`
+	GrpcNodeURLs                 []string           `yaml:"GrpcNodeUrls" env:"GRPCNODEURLS" env-separator:","`
+	GrpcReconnectMaxBackoff      time.Duration      `yaml:"GrpcReconnectMaxBackoff" env:"GRPC-RECONNECT-MAX-BACKOFF" env-default:"60s"`
+	WhaleThreshold               float64            `yaml:"WhaleThreshold" env:"WHALETHRESHOLD" env-default:"0.05"`
+	WhaleDepthRange              float64            `yaml:"WhaleDepthRange" env:"WHALE-DEPTH-RANGE" env-default:"0"`
+	WhaleOrdersThreshold         int                `yaml:"WhaleOrdersThreshold" env:"WHALEORDERSTHRESHOLD" env-default:"100"`
+	WhaleStrategy                string             `yaml:"WhaleStrategy" env:"WHALE-STRATEGY" env-default:"book"`
+	WhaleMarketStrategies        map[string]string  `yaml:"WhaleMarketStrategies" env:"WHALE-MARKET-STRATEGIES"`
+	WhaleVolumeWindows           []time.Duration    `yaml:"WhaleVolumeWindows" env:"WHALE-VOLUME-WINDOWS" env-separator:"," env-default:"1h,24h"`
+	WhaleVolumeMultiple          float64            `yaml:"WhaleVolumeMultiple" env:"WHALE-VOLUME-MULTIPLE" env-default:"10"`
+	WhaleTradeWindow             time.Duration      `yaml:"WhaleTradeWindow" env:"WHALE-TRADE-WINDOW" env-default:"5s"`
+	WhaleVolumePercentile        float64            `yaml:"WhaleVolumePercentile" env:"WHALE-VOLUME-PERCENTILE" env-default:"99"`
+	RektCascadeWindow            time.Duration      `yaml:"RektCascadeWindow" env:"REKT-CASCADE-WINDOW" env-default:"10s"`
+	RektMinNotional              float64            `yaml:"RektMinNotional" env:"REKT-MIN-NOTIONAL" env-default:"0"`
+	RektCloseOutGoodEnabled      bool               `yaml:"RektCloseOutGoodEnabled" env:"REKT-CLOSE-OUT-GOOD-ENABLED" env-default:"false"`
+	RektCloseOutGoodMinNotional  float64            `yaml:"RektCloseOutGoodMinNotional" env:"REKT-CLOSE-OUT-GOOD-MIN-NOTIONAL" env-default:"0"`
+	CollateralThresholds         map[string]float64 `yaml:"CollateralThresholds" env:"COLLATERAL-THRESHOLDS"`
+	CollateralDefaultThreshold   float64            `yaml:"CollateralDefaultThreshold" env:"COLLATERAL-DEFAULT-THRESHOLD" env-default:"0"`
+	CollateralAggregationWindow  time.Duration      `yaml:"CollateralAggregationWindow" env:"COLLATERAL-AGGREGATION-WINDOW" env-default:"1h"`
+	EthereumExplorerURLs         map[string]string  `yaml:"EthereumExplorerUrls" env:"ETHEREUM-EXPLORER-URLS"`
+	NetworkParametersAllow       []string           `yaml:"NetworkParametersAllow" env:"NETWORK-PARAMETERS-ALLOW" env-separator:","`
+	NetworkParametersDeny        []string           `yaml:"NetworkParametersDeny" env:"NETWORK-PARAMETERS-DENY" env-separator:","`
+	ErrorMaxRetries              int                `yaml:"ErrorMaxRetries" env:"ERROR-MAX-RETRIES" env-default:"3"`
+	ErrorRetryDelay              time.Duration      `yaml:"ErrorRetryDelay" env:"ERROR-RETRY-DELAY" env-default:"1s"`
+	ErrorMaxRetryTime            time.Duration      `yaml:"ErrorMaxRetryTime" env:"ERROR-MAX-RETRY-TIME" env-default:"3s"`
+	SentryEnabled                bool               `yaml:"SentryEnabled" env:"SENTRY-ENABLED" env-default:"false"`
+	SentryDsn                    string             `yaml:"SentryDsn" env:"SENTRY-DSN" env-default:""`
+	PrometheusEnabled            bool               `yaml:"PrometheusEnabled" env:"PROMETHEUS-ENABLED" env-default:"false"`
+	PrometheusPort               int                `yaml:"PrometheusPort" env:"PROMETHEUS-PORT" env-default:"2112"`
+	VegaEventsBatchSize          int64              `yaml:"VegaEventsBatchSize" env:"BATCH-SIZE" env-default:"5000"`
+	VegaOrdersEnabled            bool               `yaml:"VegaOrdersEnabled" env:"ORDERS-ENABLE" env-default:"false"`
+	VegaTradesEnabled            bool               `yaml:"VegaTradesEnabled" env:"TRADES-ENABLE" env-default:"false"`
+	VegaProposalsEnabled         bool               `yaml:"VegaProposalsEnabled" env:"PROPOSALS-ENABLE" env-default:"false"`
+	VegaVotesEnabled             bool               `yaml:"VegaVotesEnabled" env:"VOTES-ENABLE" env-default:"false"`
+	ProposalReminderBefore       time.Duration      `yaml:"ProposalReminderBefore" env:"PROPOSAL-REMINDER-BEFORE" env-default:"24h"`
+	VegaMarketCreatedEnabled     bool               `yaml:"VegaMarketCreatedEnabled" env:"MARKET-CREATED-ENABLE" env-default:"false"`
+	VegaMarketUpdatedEnabled     bool               `yaml:"VegaMarketUpdatedEnabled" env:"MARKET-UPDATED-ENABLE" env-default:"false"`
+	VegaCollateralEnabled        bool               `yaml:"VegaCollateralEnabled" env:"COLLATERAL-ENABLE" env-default:"false"`
+	VegaAuctionsEnabled          bool               `yaml:"VegaAuctionsEnabled" env:"AUCTION-ENABLE" env-default:"false"`
+	VegaAuctionsExtendEnabled    bool               `yaml:"VegaAuctionsExtendEnabled" env:"AUCTION-EXTEND-ENABLE" env-default:"false"`
+	VegaLossSocializationEnabled bool               `yaml:"VegaLossSocializationEnabled" env:"LOSS-SOCIALIZATION-ENABLE" env-default:"false"`
+	VegaNetworkParametersEnabled bool               `yaml:"VegaNetworkParametersEnabled" env:"NETWORK-PARAMETERS-ENABLE" env-default:"false"`
+	BotBlacklistEnabled          bool               `yaml:"BotBlacklistEnabled" env:"BOT-BLACKLIST-ENABLE" env-default:"false"`
+	Debug                        bool               `yaml:"Debug" env:"DEBUG" env-default:"false"`
+}
+
+// NodeURLs returns the ordered list of Vega data nodes to connect to
+func (cfg ConfigVars) NodeURLs() []string {
+	if len(cfg.GrpcNodeURLs) > 0 {
+		return cfg.GrpcNodeURLs
+	}
+	return []string{cfg.GrpcNodeURL}
 }
 
 // ReadConfig import config struct from yaml file
@@ -36,5 +90,17 @@ func ReadConfig(path string) (ConfigVars, error) {
 	if err != nil {
 		return cfg, err
 	}
+
+	strategies := []string{cfg.WhaleStrategy}
+	for _, strategy := range cfg.WhaleMarketStrategies {
+		strategies = append(strategies, strategy)
+	}
+	for _, strategy := range strategies {
+		switch strategy {
+		case eventhandlers.WhaleStrategyBook, eventhandlers.WhaleStrategyAverage, eventhandlers.WhaleStrategyPercentile:
+		default:
+			return cfg, errors.New("unknown whale strategy " + strategy)
+		}
+	}
 	return cfg, nil
 }
@@ -0,0 +1,94 @@
+package errorpolicy
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Class tells the consumer loop what to do with a failed event
+type Class int
+
+const (
+	// Transient errors are retried
+	Transient Class = iota
+	// Permanent errors skip the event
+	Permanent
+	// Fatal errors stop the bot
+	Fatal
+)
+
+func (class Class) String() string {
+	switch class {
+	case Transient:
+		return "transient"
+	case Permanent:
+		return "permanent"
+	case Fatal:
+		return "fatal"
+	}
+	return "unknown"
+}
+
+type classifiedError struct {
+	class Class
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// NewTransient marks an error as transient
+func NewTransient(err error) error {
+	return classify(err, Transient)
+}
+
+// NewPermanent marks an error as permanent
+func NewPermanent(err error) error {
+	return classify(err, Permanent)
+}
+
+// NewFatal marks an error as fatal
+func NewFatal(err error) error {
+	return classify(err, Fatal)
+}
+
+func classify(err error, class Class) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// Classify returns the class of an error. Errors that have not been explicitly
+// classified are classified from their gRPC status code, unknown errors are
+// permanent
+func Classify(err error) Class {
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+
+	for cause := err; cause != nil; cause = errors.Unwrap(cause) {
+		grpcErr, ok := cause.(interface{ GRPCStatus() *status.Status })
+		if !ok {
+			continue
+		}
+
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unknown:
+			return Transient
+		case codes.Unauthenticated, codes.PermissionDenied:
+			return Fatal
+		}
+		return Permanent
+	}
+
+	return Permanent
+}
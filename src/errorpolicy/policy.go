@@ -0,0 +1,134 @@
+package errorpolicy
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+)
+
+var (
+	skippedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vegabot_skipped_events_total",
+		Help: "Number of bus events skipped because of a handler error",
+	}, []string{"event_type", "class"})
+	retriedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vegabot_retried_events_total",
+		Help: "Number of bus event handling retries",
+	}, []string{"event_type"})
+)
+
+// Policy decides how handler errors are retried, reported and counted
+type Policy struct {
+	maxRetries    int
+	retryDelay    time.Duration
+	maxRetryTime  time.Duration
+	sentryEnabled bool
+}
+
+// NewPolicy creates an error policy. Transient errors are retried up to
+// maxRetries times, waiting retryDelay doubled on every attempt. The retries
+// block the event stream, so the waits of an event never exceed maxRetryTime
+func NewPolicy(maxRetries int, retryDelay time.Duration, maxRetryTime time.Duration, sentryEnabled bool) *Policy {
+	return &Policy{
+		maxRetries:    maxRetries,
+		retryDelay:    retryDelay,
+		maxRetryTime:  maxRetryTime,
+		sentryEnabled: sentryEnabled,
+	}
+}
+
+// Run calls fn for a bus event applying the policy. Only fatal errors are
+// returned, any other error is reported and the event skipped
+func (policy *Policy) Run(event *proto.BusEvent, fn func() error) error {
+	delay := policy.retryDelay
+	remaining := policy.maxRetryTime
+	for attempt := 0; ; attempt++ {
+		err := safeCall(fn)
+		if err == nil {
+			return nil
+		}
+
+		if delay > remaining {
+			delay = remaining
+		}
+		class := Classify(err)
+		if class == Transient && attempt < policy.maxRetries && delay > 0 {
+			log.Printf("Transient error handling event %s (%s), retrying in %s: %s\n", event.Id, event.Type, delay, err)
+			retriedEvents.WithLabelValues(event.Type.String()).Inc()
+			time.Sleep(delay)
+			remaining -= delay
+			delay = delay * 2
+			continue
+		}
+
+		policy.Report(event, err, class)
+		if class == Fatal {
+			return err
+		}
+
+		log.Printf("Skipping event %s (%s) after %s error: %s\n", event.Id, event.Type, class, err)
+		skippedEvents.WithLabelValues(event.Type.String(), class.String()).Inc()
+		return nil
+	}
+}
+
+// Report sends an event handling error to Sentry with the bus event as context
+func (policy *Policy) Report(event *proto.BusEvent, err error, class Class) {
+	if !policy.sentryEnabled {
+		return
+	}
+
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(map[string]string{
+			"bus_event_id":   event.Id,
+			"bus_event_type": event.Type.String(),
+			"market_id":      MarketID(event),
+			"error_class":    class.String(),
+		})
+		scope.SetExtra("block", event.Block)
+		if class == Fatal {
+			scope.SetLevel(sentry.LevelFatal)
+		}
+		sentry.CaptureException(err)
+	})
+}
+
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPermanent(fmt.Errorf("handler panic: %v", r))
+		}
+	}()
+
+	return fn()
+}
+
+// MarketID returns the market a bus event refers to, if any
+func MarketID(event *proto.BusEvent) string {
+	switch event.Type {
+	case proto.BusEventType_BUS_EVENT_TYPE_ORDER:
+		return event.GetOrder().GetMarketId()
+	case proto.BusEventType_BUS_EVENT_TYPE_TRADE:
+		return event.GetTrade().GetMarketId()
+	case proto.BusEventType_BUS_EVENT_TYPE_AUCTION:
+		return event.GetAuction().GetMarketId()
+	case proto.BusEventType_BUS_EVENT_TYPE_LOSS_SOCIALIZATION:
+		return event.GetLossSocialization().GetMarketId()
+	case proto.BusEventType_BUS_EVENT_TYPE_MARKET_DATA:
+		return event.GetMarketData().GetMarket()
+	case proto.BusEventType_BUS_EVENT_TYPE_MARKET_CREATED:
+		return event.GetMarketCreated().GetId()
+	case proto.BusEventType_BUS_EVENT_TYPE_MARKET_UPDATED:
+		return event.GetMarketUpdated().GetId()
+	case proto.BusEventType_BUS_EVENT_TYPE_SETTLE_DISTRESSED:
+		return event.GetSettleDistressed().GetMarketId()
+	case proto.BusEventType_BUS_EVENT_TYPE_POSITION_RESOLUTION:
+		return event.GetPositionResolution().GetMarketId()
+	}
+	return ""
+}
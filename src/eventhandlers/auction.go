@@ -0,0 +1,97 @@
+package eventhandlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/baldator/vega-bot/auctions"
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+// AuctionHandler notifies auctions starting, being extended and ending
+type AuctionHandler struct {
+	dataClient    api.TradingDataServiceClient
+	markets       *marketcache.Cache
+	tracker       *auctions.Tracker
+	extendEnabled bool
+}
+
+// NewAuctionHandler creates an auction alert handler
+func NewAuctionHandler(dataClient api.TradingDataServiceClient, markets *marketcache.Cache, tracker *auctions.Tracker, extendEnabled bool) *AuctionHandler {
+	return &AuctionHandler{
+		dataClient:    dataClient,
+		markets:       markets,
+		tracker:       tracker,
+		extendEnabled: extendEnabled,
+	}
+}
+
+// EventTypes returns the bus event types handled by AuctionHandler
+func (handler *AuctionHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{proto.BusEventType_BUS_EVENT_TYPE_AUCTION}
+}
+
+// Handle returns an auction notification. Starts report the indicative
+// price and volume, ends report the auction duration. The tracker is updated
+// once the notification is built, so a retried event is reported the same way
+func (handler *AuctionHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	auction := event.GetAuction()
+	tracked, active := handler.tracker.Auction(auction.MarketId)
+	var details socialevents.AuctionDetails
+
+	if auction.Leave {
+		if !active {
+			log.Printf("Ignoring the end of an unknown auction on market %s\n", auction.MarketId)
+			return nil, nil
+		}
+		details.Status = "ended"
+		end := auction.End
+		if end == 0 {
+			end = time.Now().UnixNano()
+		}
+		if tracked.Start > 0 && end > tracked.Start {
+			details.Duration = time.Duration(end - tracked.Start).Round(time.Second)
+		}
+	} else {
+		details.Status = "started"
+		if active {
+			if !handler.extendEnabled {
+				_, err := handler.tracker.Start(auction)
+				return nil, err
+			}
+			details.Status = "extended"
+		}
+
+		response, err := handler.dataClient.MarketDataByID(ctx, &api.MarketDataByIDRequest{MarketId: auction.MarketId})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get market data for market %s", auction.MarketId)
+		}
+		details.IndicativePrice = response.GetMarketData().GetIndicativePrice()
+		details.IndicativeVolume = response.GetMarketData().GetIndicativeVolume()
+	}
+
+	notification, err := socialevents.AuctionNotification(handler.markets, auction, details)
+	if err != nil {
+		return nil, err
+	}
+
+	if auction.Leave {
+		_, _, err = handler.tracker.End(auction)
+	} else {
+		_, err = handler.tracker.Start(auction)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if notification.Message == "" {
+		return nil, nil
+	}
+
+	return []socialevents.Notification{notification}, nil
+}
@@ -0,0 +1,230 @@
+package eventhandlers
+
+import (
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// defaultEthereumExplorers are the block explorers of the Ethereum chains by chain ID
+var defaultEthereumExplorers = map[string]string{
+	"1":  "https://etherscan.io",
+	"3":  "https://ropsten.etherscan.io",
+	"4":  "https://rinkeby.etherscan.io",
+	"5":  "https://goerli.etherscan.io",
+	"42": "https://kovan.etherscan.io",
+}
+
+// CollateralSettings configures deposit and withdrawal alerts
+type CollateralSettings struct {
+	// Thresholds is the amount to exceed, in asset units, by asset ID or symbol
+	Thresholds map[string]float64
+	// DefaultThreshold applies to the assets without threshold, 0 disables their alerts
+	DefaultThreshold float64
+	// Window is the time during which the small movements of a party are aggregated
+	Window time.Duration
+	// EthereumExplorers overrides the block explorer base URL by Ethereum chain ID
+	EthereumExplorers map[string]string
+}
+
+// movements aggregates the small deposits or withdrawals of a party in an asset
+type movements struct {
+	movement socialevents.CollateralMovement
+	first    time.Time
+}
+
+// CollateralHandler notifies large deposits and withdrawals through the
+// Ethereum bridge
+type CollateralHandler struct {
+	markets   *marketcache.Cache
+	settings  CollateralSettings
+	ethereum  socialevents.EthereumConfig
+	movements map[string]*movements
+}
+
+// NewCollateralHandler creates a deposit and withdrawal handler. ethereumConfig
+// is the current blockchains.ethereumConfig network parameter, it can be nil
+func NewCollateralHandler(markets *marketcache.Cache, settings CollateralSettings, ethereumConfig *proto.NetworkParameter) *CollateralHandler {
+	explorers := make(map[string]string)
+	for chainID, url := range defaultEthereumExplorers {
+		explorers[chainID] = url
+	}
+	for chainID, url := range settings.EthereumExplorers {
+		explorers[chainID] = strings.TrimRight(url, "/")
+	}
+	settings.EthereumExplorers = explorers
+
+	handler := &CollateralHandler{
+		markets:   markets,
+		settings:  settings,
+		movements: make(map[string]*movements),
+	}
+	if ethereumConfig != nil {
+		handler.setEthereumConfig(ethereumConfig.Value)
+	}
+	return handler
+}
+
+// EventTypes returns the bus event types handled by CollateralHandler
+func (handler *CollateralHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{
+		proto.BusEventType_BUS_EVENT_TYPE_DEPOSIT,
+		proto.BusEventType_BUS_EVENT_TYPE_WITHDRAWAL,
+		proto.BusEventType_BUS_EVENT_TYPE_NETWORK_PARAMETER,
+	}
+}
+
+// Handle returns the notifications of the finalized deposits and withdrawals
+// exceeding the threshold of their asset, alone or aggregated
+func (handler *CollateralHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	switch event.Type {
+	case proto.BusEventType_BUS_EVENT_TYPE_NETWORK_PARAMETER:
+		if parameter := event.GetNetworkParameter(); parameter != nil && parameter.Key == ethereumConfigKey {
+			handler.setEthereumConfig(parameter.Value)
+		}
+	case proto.BusEventType_BUS_EVENT_TYPE_DEPOSIT:
+		deposit := event.GetDeposit()
+		if deposit.Status != proto.Deposit_STATUS_FINALIZED {
+			return nil, nil
+		}
+		return handler.handleMovement(ctx, socialevents.DepositNotificationType, deposit.PartyId, deposit.Asset, deposit.Amount, deposit.TxHash, movementTime(deposit.CreditedTimestamp, deposit.CreatedTimestamp))
+	case proto.BusEventType_BUS_EVENT_TYPE_WITHDRAWAL:
+		withdrawal := event.GetWithdrawal()
+		if withdrawal.Status != proto.Withdrawal_STATUS_FINALIZED {
+			return nil, nil
+		}
+		return handler.handleMovement(ctx, socialevents.WithdrawalNotificationType, withdrawal.PartyId, withdrawal.Asset, strconv.FormatUint(withdrawal.Amount, 10), withdrawal.TxHash, movementTime(withdrawal.WithdrawnTimestamp, withdrawal.CreatedTimestamp))
+	}
+	return nil, nil
+}
+
+// handleMovement notifies a movement above the threshold of its asset. Smaller
+// movements of the same party are summed over the window and notified together
+// once their total exceeds the threshold
+func (handler *CollateralHandler) handleMovement(ctx context.Context, notificationType string, partyID string, assetID string, amount string, txHash string, at time.Time) ([]socialevents.Notification, error) {
+	asset, err := handler.markets.Asset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	handler.expireMovements(at)
+	threshold := handler.threshold(asset)
+	if threshold <= 0 {
+		return nil, nil
+	}
+	value, ok := assetAmount(amount, asset.Decimals)
+	if !ok {
+		log.Printf("Invalid %s amount %q of party %s\n", notificationType, amount, partyID)
+		return nil, nil
+	}
+
+	movement := socialevents.CollateralMovement{
+		PartyID: partyID,
+		Asset:   asset,
+		Amount:  value,
+		Count:   1,
+		TxHash:  txHash,
+		TxLink:  handler.txLink(txHash),
+	}
+	if movement.Amount < threshold {
+		key := notificationType + "/" + partyID + "/" + assetID
+		aggregate, ok := handler.movements[key]
+		if !ok || at.Sub(aggregate.first) > handler.settings.Window {
+			handler.movements[key] = &movements{movement: movement, first: at}
+			return nil, nil
+		}
+		aggregate.movement.Amount += movement.Amount
+		aggregate.movement.Count++
+		aggregate.movement.TxHash = movement.TxHash
+		aggregate.movement.TxLink = movement.TxLink
+		if aggregate.movement.Amount < threshold {
+			return nil, nil
+		}
+		delete(handler.movements, key)
+		movement = aggregate.movement
+	}
+
+	var notification socialevents.Notification
+	if notificationType == socialevents.DepositNotificationType {
+		notification, err = socialevents.DepositNotification(movement)
+	} else {
+		notification, err = socialevents.WithdrawalNotification(movement)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []socialevents.Notification{notification}, nil
+}
+
+// expireMovements forgets the small movements whose window is over, their
+// total stayed below the threshold
+func (handler *CollateralHandler) expireMovements(now time.Time) {
+	for key, aggregate := range handler.movements {
+		if now.Sub(aggregate.first) > handler.settings.Window {
+			delete(handler.movements, key)
+		}
+	}
+}
+
+func (handler *CollateralHandler) threshold(asset *proto.Asset) float64 {
+	if threshold, ok := handler.settings.Thresholds[asset.Id]; ok {
+		return threshold
+	}
+	if threshold, ok := handler.settings.Thresholds[asset.Symbol]; ok {
+		return threshold
+	}
+	return handler.settings.DefaultThreshold
+}
+
+// txLink returns the block explorer link of an Ethereum transaction, or of the
+// bridge contract when the transaction hash is unknown
+func (handler *CollateralHandler) txLink(txHash string) string {
+	explorer, ok := handler.settings.EthereumExplorers[handler.ethereum.ChainID]
+	if !ok {
+		return ""
+	}
+	if txHash != "" {
+		return explorer + "/tx/" + txHash
+	}
+	if handler.ethereum.BridgeAddress != "" {
+		return explorer + "/address/" + handler.ethereum.BridgeAddress
+	}
+	return ""
+}
+
+// assetAmount converts an integer amount, which can exceed 64 bits for the
+// ERC20 tokens with 18 decimals, to asset units
+func assetAmount(amount string, decimals uint64) (float64, bool) {
+	value, ok := new(big.Float).SetString(amount)
+	if !ok || value.Sign() < 0 {
+		return 0, false
+	}
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), new(big.Int).SetUint64(decimals), nil))
+	units, _ := value.Quo(value, scale).Float64()
+	return units, true
+}
+
+// movementTime returns when a movement was finalized, or created when the
+// finalization time is not set
+func movementTime(finalized int64, created int64) time.Time {
+	if finalized == 0 {
+		return time.Unix(0, created)
+	}
+	return time.Unix(0, finalized)
+}
+
+func (handler *CollateralHandler) setEthereumConfig(value string) {
+	config, err := socialevents.ParseEthereumConfig(value)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	handler.ethereum = config
+}
@@ -0,0 +1,77 @@
+package eventhandlers
+
+import (
+	"github.com/baldator/vega-bot/errorpolicy"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// EventHandler turns Vega bus events into social notifications
+type EventHandler interface {
+	// EventTypes returns the bus event types the handler wants to receive
+	EventTypes() []proto.BusEventType
+	// Handle processes a bus event and returns the notifications to publish
+	Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error)
+}
+
+// Registry dispatches bus events to the registered handlers
+type Registry struct {
+	handlers   map[proto.BusEventType][]EventHandler
+	eventTypes []proto.BusEventType
+	policy     *errorpolicy.Policy
+}
+
+// NewRegistry creates an empty handler registry. Handler errors are managed by policy
+func NewRegistry(policy *errorpolicy.Policy) *Registry {
+	return &Registry{
+		handlers: make(map[proto.BusEventType][]EventHandler),
+		policy:   policy,
+	}
+}
+
+// Register adds a handler for all the event types it declares
+func (registry *Registry) Register(handler EventHandler) {
+	for _, eventType := range handler.EventTypes() {
+		if _, ok := registry.handlers[eventType]; !ok {
+			registry.eventTypes = append(registry.eventTypes, eventType)
+		}
+		registry.handlers[eventType] = append(registry.handlers[eventType], handler)
+	}
+}
+
+// EventTypes returns the bus event types needed by the registered handlers
+func (registry *Registry) EventTypes() []proto.BusEventType {
+	return registry.eventTypes
+}
+
+// Dispatch sends the event to every handler registered for its type. A failing
+// handler does not prevent the other handlers from running, only fatal errors
+// are returned
+func (registry *Registry) Dispatch(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	var notifications []socialevents.Notification
+	for _, handler := range registry.handlers[event.Type] {
+		var handlerNotifications []socialevents.Notification
+		err := registry.policy.Run(event, func() error {
+			// the notifications of a failed attempt are dropped
+			result, err := handler.Handle(ctx, event)
+			if err != nil {
+				return err
+			}
+			handlerNotifications = result
+			return nil
+		})
+		if err != nil {
+			return notifications, err
+		}
+		for i := range handlerNotifications {
+			if handlerNotifications[i].EventID == "" {
+				handlerNotifications[i].EventID = event.Id
+			}
+		}
+		notifications = append(notifications, handlerNotifications...)
+	}
+
+	return notifications, nil
+}
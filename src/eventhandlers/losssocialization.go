@@ -0,0 +1,35 @@
+package eventhandlers
+
+import (
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// LossSocializationHandler notifies the distribution of funds generated by defaulting traders
+type LossSocializationHandler struct {
+	markets *marketcache.Cache
+}
+
+// NewLossSocializationHandler creates a loss socialization handler
+func NewLossSocializationHandler(markets *marketcache.Cache) *LossSocializationHandler {
+	return &LossSocializationHandler{markets: markets}
+}
+
+// EventTypes returns the bus event types handled by LossSocializationHandler
+func (handler *LossSocializationHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{proto.BusEventType_BUS_EVENT_TYPE_LOSS_SOCIALIZATION}
+}
+
+// Handle returns a loss socialization notification
+func (handler *LossSocializationHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	lossSocialization := event.GetLossSocialization()
+	notification, err := socialevents.LossSocializationNotification(handler.markets, lossSocialization)
+	if err != nil {
+		return nil, err
+	}
+
+	return []socialevents.Notification{notification}, nil
+}
@@ -0,0 +1,91 @@
+package eventhandlers
+
+import (
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// MarketHandler announces market creations and market configuration updates
+type MarketHandler struct {
+	markets  *marketcache.Cache
+	created  bool
+	updated  bool
+	previous map[string]socialevents.MarketDetails
+}
+
+// NewMarketHandler creates a market handler. created and updated enable the
+// announcement of market creations and of market updates. The markets already
+// in the cache are the reference to detect the changes of the first updates
+func NewMarketHandler(markets *marketcache.Cache, created bool, updated bool) *MarketHandler {
+	handler := &MarketHandler{
+		markets:  markets,
+		created:  created,
+		updated:  updated,
+		previous: make(map[string]socialevents.MarketDetails),
+	}
+	for _, market := range markets.Markets() {
+		handler.previous[market.Id] = socialevents.NewMarketDetails(markets, market)
+	}
+	return handler
+}
+
+// EventTypes returns the bus event types handled by MarketHandler
+func (handler *MarketHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{
+		proto.BusEventType_BUS_EVENT_TYPE_MARKET_CREATED,
+		proto.BusEventType_BUS_EVENT_TYPE_MARKET_UPDATED,
+	}
+}
+
+// Handle returns a market created or market updated notification
+func (handler *MarketHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	switch event.Type {
+	case proto.BusEventType_BUS_EVENT_TYPE_MARKET_CREATED:
+		return handler.handleCreated(event.GetMarketCreated())
+	case proto.BusEventType_BUS_EVENT_TYPE_MARKET_UPDATED:
+		return handler.handleUpdated(event.GetMarketUpdated())
+	}
+	return nil, nil
+}
+
+func (handler *MarketHandler) handleCreated(market *proto.Market) ([]socialevents.Notification, error) {
+	handler.previous[market.Id] = socialevents.NewMarketDetails(handler.markets, market)
+	if !handler.created {
+		return nil, nil
+	}
+
+	notification, err := socialevents.MarketCreationNotification(handler.markets, market)
+	if err != nil {
+		return nil, err
+	}
+	return []socialevents.Notification{notification}, nil
+}
+
+// handleUpdated announces the configuration changes of a market. Updates of
+// markets never seen before and updates changing none of the announced
+// details, like trading mode changes, are not notified
+func (handler *MarketHandler) handleUpdated(market *proto.Market) ([]socialevents.Notification, error) {
+	details := socialevents.NewMarketDetails(handler.markets, market)
+	previous, ok := handler.previous[market.Id]
+	if !handler.updated || !ok {
+		handler.previous[market.Id] = details
+		return nil, nil
+	}
+
+	changes := details.Changes(previous)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	// the reference is kept until the notification is built, so a retried
+	// event reports the same changes
+	notification, err := socialevents.MarketUpdateNotification(handler.markets, market, changes)
+	if err != nil {
+		return nil, err
+	}
+	handler.previous[market.Id] = details
+	return []socialevents.Notification{notification}, nil
+}
@@ -0,0 +1,39 @@
+package eventhandlers
+
+import (
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// MarketCacheHandler keeps the market cache up to date
+type MarketCacheHandler struct {
+	markets *marketcache.Cache
+}
+
+// NewMarketCacheHandler creates a handler refreshing markets on creation and update
+func NewMarketCacheHandler(markets *marketcache.Cache) *MarketCacheHandler {
+	return &MarketCacheHandler{markets: markets}
+}
+
+// EventTypes returns the bus event types handled by MarketCacheHandler
+func (handler *MarketCacheHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{
+		proto.BusEventType_BUS_EVENT_TYPE_MARKET_CREATED,
+		proto.BusEventType_BUS_EVENT_TYPE_MARKET_UPDATED,
+	}
+}
+
+// Handle stores the new version of the market
+func (handler *MarketCacheHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	switch event.Type {
+	case proto.BusEventType_BUS_EVENT_TYPE_MARKET_CREATED:
+		handler.markets.Update(event.GetMarketCreated())
+	case proto.BusEventType_BUS_EVENT_TYPE_MARKET_UPDATED:
+		handler.markets.Update(event.GetMarketUpdated())
+	}
+
+	return nil, nil
+}
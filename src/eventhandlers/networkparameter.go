@@ -0,0 +1,75 @@
+package eventhandlers
+
+import (
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+const ethereumConfigKey = "blockchains.ethereumConfig"
+
+// NetworkParameterHandler notifies changes of the Ethereum network configuration
+type NetworkParameterHandler struct {
+	current  *proto.NetworkParameter
+	onChange func(*proto.NetworkParameter) error
+}
+
+// NewNetworkParameterHandler creates a network parameter handler. onChange is called with the new Ethereum configuration after a change is notified
+func NewNetworkParameterHandler(current *proto.NetworkParameter, onChange func(*proto.NetworkParameter) error) *NetworkParameterHandler {
+	return &NetworkParameterHandler{
+		current:  current,
+		onChange: onChange,
+	}
+}
+
+// EventTypes returns the bus event types handled by NetworkParameterHandler
+func (handler *NetworkParameterHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{proto.BusEventType_BUS_EVENT_TYPE_NETWORK_PARAMETER}
+}
+
+// Handle returns a notification when a field of the Ethereum configuration
+// changes. A valid configuration becomes the reference for the next changes,
+// even when the previous one was invalid
+func (handler *NetworkParameterHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	networkParameter := event.GetNetworkParameter()
+	if networkParameter == nil || networkParameter.Key != ethereumConfigKey {
+		return nil, nil
+	}
+
+	notification, err := socialevents.NetworkParametesNotification(networkParameter, handler.current)
+	if err != nil {
+		if _, invalid := socialevents.ParseEthereumConfig(networkParameter.Value); invalid != nil {
+			return nil, err
+		}
+		// the previous configuration was invalid, the new one replaces it
+		updateErr := handler.update(networkParameter)
+		if updateErr != nil {
+			return nil, updateErr
+		}
+		return nil, err
+	}
+	// the first configuration seen is the reference for the next changes
+	if notification.Message == "" && handler.current != nil {
+		return nil, nil
+	}
+
+	err = handler.update(networkParameter)
+	if err != nil {
+		return nil, err
+	}
+
+	if notification.Message == "" {
+		return nil, nil
+	}
+	return []socialevents.Notification{notification}, nil
+}
+
+// update makes a configuration the reference for the next changes
+func (handler *NetworkParameterHandler) update(networkParameter *proto.NetworkParameter) error {
+	handler.current = networkParameter
+	if handler.onChange != nil {
+		return handler.onChange(networkParameter)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package eventhandlers
+
+import (
+	"log"
+	"path"
+
+	"github.com/baldator/vega-bot/socialevents"
+	"github.com/baldator/vega-bot/state"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+// ParameterWatchSettings selects the network parameters to announce. Keys are
+// matched with path.Match patterns, for example market.fee.*
+type ParameterWatchSettings struct {
+	// Allow lists the parameters to announce, all of them when empty
+	Allow []string
+	// Deny lists the parameters never announced, it takes precedence over Allow
+	Deny []string
+}
+
+// ParameterWatchHandler notifies the changes of every network parameter. The
+// Ethereum configuration is left to NetworkParameterHandler
+type ParameterWatchHandler struct {
+	dataClient api.TradingDataServiceClient
+	store      state.StateStore
+	settings   ParameterWatchSettings
+	snapshot   map[string]string
+}
+
+// NewParameterWatchHandler creates a network parameter watcher comparing the
+// changes with the snapshot kept in store
+func NewParameterWatchHandler(dataClient api.TradingDataServiceClient, store state.StateStore, settings ParameterWatchSettings) *ParameterWatchHandler {
+	return &ParameterWatchHandler{
+		dataClient: dataClient,
+		store:      store,
+		settings:   settings,
+		snapshot:   make(map[string]string),
+	}
+}
+
+// Sync reads all the network parameters and returns the notifications of the
+// changes made since the stored snapshot, which is then replaced. Nothing is
+// notified the first time, when there is no stored snapshot
+func (handler *ParameterWatchHandler) Sync(ctx context.Context) ([]socialevents.Notification, error) {
+	stored, err := handler.store.NetworkParameters()
+	if err != nil {
+		return nil, err
+	}
+	response, err := handler.dataClient.NetworkParameters(ctx, &api.NetworkParametersRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get network parameters")
+	}
+
+	announce := len(stored) > 0
+	handler.snapshot = stored
+	var notifications []socialevents.Notification
+	for _, parameter := range response.NetworkParameters {
+		notification, changed, err := handler.update(parameter, announce)
+		if err != nil {
+			return notifications, err
+		}
+		if changed {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
+
+// EventTypes returns the bus event types handled by ParameterWatchHandler
+func (handler *ParameterWatchHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{proto.BusEventType_BUS_EVENT_TYPE_NETWORK_PARAMETER}
+}
+
+// Handle returns a notification when a watched network parameter changes
+func (handler *ParameterWatchHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	parameter := event.GetNetworkParameter()
+	if parameter == nil {
+		return nil, nil
+	}
+	notification, changed, err := handler.update(parameter, true)
+	if err != nil || !changed {
+		return nil, err
+	}
+	return []socialevents.Notification{notification}, nil
+}
+
+// update records the value of a parameter. changed is true when the value
+// differs from the snapshot and the change must be announced
+func (handler *ParameterWatchHandler) update(parameter *proto.NetworkParameter, announce bool) (notification socialevents.Notification, changed bool, err error) {
+	previous, known := handler.snapshot[parameter.Key]
+	if known && previous == parameter.Value {
+		return notification, false, nil
+	}
+
+	changed = announce && handler.watched(parameter.Key)
+	if changed {
+		notification, err = socialevents.NetworkParameterChangeNotification(parameter.Key, previous, parameter.Value)
+		if err != nil {
+			return notification, false, err
+		}
+	}
+
+	err = handler.store.SetNetworkParameter(parameter.Key, parameter.Value)
+	if err != nil {
+		return notification, false, err
+	}
+	handler.snapshot[parameter.Key] = parameter.Value
+	return notification, changed, nil
+}
+
+// watched tells if the changes of a parameter are announced
+func (handler *ParameterWatchHandler) watched(key string) bool {
+	if key == ethereumConfigKey {
+		return false
+	}
+	if matchAny(handler.settings.Deny, key) {
+		return false
+	}
+	return len(handler.settings.Allow) == 0 || matchAny(handler.settings.Allow, key)
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			log.Printf("Invalid network parameter pattern %q\n", pattern)
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package eventhandlers
+
+import (
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// ProposalHandler notifies governance proposal state changes
+type ProposalHandler struct {
+	markets *marketcache.Cache
+}
+
+// NewProposalHandler creates a governance proposal handler
+func NewProposalHandler(markets *marketcache.Cache) *ProposalHandler {
+	return &ProposalHandler{markets: markets}
+}
+
+// EventTypes returns the bus event types handled by ProposalHandler
+func (handler *ProposalHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{proto.BusEventType_BUS_EVENT_TYPE_PROPOSAL}
+}
+
+// Handle returns a proposal notification
+func (handler *ProposalHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	notification, err := socialevents.ProposalNotification(handler.markets, event.GetProposal())
+	if err != nil {
+		return nil, err
+	}
+
+	return []socialevents.Notification{notification}, nil
+}
@@ -0,0 +1,240 @@
+package eventhandlers
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+// networkParty is the party taking over the positions of distressed parties
+const networkParty = "network"
+
+// RektSettings configures rekt alerts
+type RektSettings struct {
+	// Window is the time during which the close outs of a market are reported together
+	Window time.Duration
+	// BadMinNotional is the notional below which bad close outs are ignored
+	BadMinNotional float64
+	// GoodEnabled enables alerts on good close outs, covered by the margin of the distressed party
+	GoodEnabled bool
+	// GoodMinNotional is the notional below which good close outs are ignored
+	GoodMinNotional float64
+}
+
+// RektHandler notifies liquidated positions. The close outs of a market
+// happening within the cascade window are reported together with the
+// distressed parties and the loss socialised. The window is measured in block
+// time, cascades are flushed on the time updates of every block
+type RektHandler struct {
+	dataClient api.TradingDataServiceClient
+	markets    *marketcache.Cache
+	settings   RektSettings
+	cascades   map[string]*rektCascade
+	now        time.Time
+}
+
+// rektCascade is the close outs of a market within the window. seen holds the
+// trades and loss socialisation events already counted, a retried event is
+// not counted again
+type rektCascade struct {
+	first   time.Time
+	trades  []*proto.Trade
+	parties map[string]bool
+	seen    map[string]bool
+	data    socialevents.RektCascade
+}
+
+// NewRektHandler creates a rekt alert handler. Notionals are expressed in the
+// settlement asset of the market
+func NewRektHandler(dataClient api.TradingDataServiceClient, markets *marketcache.Cache, settings RektSettings) *RektHandler {
+	return &RektHandler{
+		dataClient: dataClient,
+		markets:    markets,
+		settings:   settings,
+		cascades:   make(map[string]*rektCascade),
+	}
+}
+
+// EventTypes returns the bus event types handled by RektHandler
+func (handler *RektHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{
+		proto.BusEventType_BUS_EVENT_TYPE_TRADE,
+		proto.BusEventType_BUS_EVENT_TYPE_SETTLE_DISTRESSED,
+		proto.BusEventType_BUS_EVENT_TYPE_LOSS_SOCIALIZATION,
+		proto.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE,
+	}
+}
+
+// Handle adds network close outs to the cascade of their market and returns
+// the notifications of the cascades whose window is over
+func (handler *RektHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	switch event.Type {
+	case proto.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE:
+		handler.now = time.Unix(0, event.GetTimeUpdate().GetTimestamp())
+	case proto.BusEventType_BUS_EVENT_TYPE_TRADE:
+		trade := event.GetTrade()
+		if trade == nil {
+			return nil, nil
+		}
+		handler.now = time.Unix(0, trade.Timestamp)
+		err := handler.addTrade(ctx, trade)
+		if err != nil {
+			return nil, err
+		}
+	case proto.BusEventType_BUS_EVENT_TYPE_SETTLE_DISTRESSED:
+		distressed := event.GetSettleDistressed()
+		if distressed == nil {
+			return nil, nil
+		}
+		cascade := handler.cascade(distressed.MarketId)
+		if !cascade.parties[distressed.PartyId] {
+			cascade.parties[distressed.PartyId] = true
+			cascade.data.Parties = append(cascade.data.Parties, distressed.PartyId)
+		}
+	case proto.BusEventType_BUS_EVENT_TYPE_LOSS_SOCIALIZATION:
+		lossSocialization := event.GetLossSocialization()
+		if lossSocialization == nil || lossSocialization.Amount >= 0 {
+			return nil, nil
+		}
+		cascade := handler.cascade(lossSocialization.MarketId)
+		if !cascade.seen[event.Id] {
+			cascade.seen[event.Id] = true
+			cascade.data.LossSocialized += uint64(-lossSocialization.Amount)
+		}
+	}
+
+	return handler.flush()
+}
+
+func (handler *RektHandler) addTrade(ctx context.Context, trade *proto.Trade) error {
+	minNotional := handler.settings.BadMinNotional
+	switch trade.Type {
+	case proto.Trade_TYPE_NETWORK_CLOSE_OUT_BAD:
+	case proto.Trade_TYPE_NETWORK_CLOSE_OUT_GOOD:
+		if !handler.settings.GoodEnabled {
+			return nil
+		}
+		minNotional = handler.settings.GoodMinNotional
+	default:
+		return nil
+	}
+
+	market, err := handler.markets.Market(ctx, trade.MarketId)
+	if err != nil {
+		return err
+	}
+	// prices are expressed with the market decimal places
+	notional := float64(trade.Size) * float64(trade.Price) / math.Pow(10, float64(market.DecimalPlaces))
+	if notional < minNotional {
+		return nil
+	}
+
+	cascade := handler.cascade(trade.MarketId)
+	if cascade.seen[trade.Id] {
+		return nil
+	}
+	if cascade.data.Count == 0 {
+		response, err := handler.dataClient.MarketDataByID(ctx, &api.MarketDataByIDRequest{MarketId: trade.MarketId})
+		if err != nil {
+			return errors.Wrapf(err, "could not get market data for market %s", trade.MarketId)
+		}
+		cascade.data.MarkPrice = response.GetMarketData().GetMarkPrice()
+		cascade.data.LowPrice = trade.Price
+		cascade.data.Type = trade.Type
+	}
+
+	cascade.seen[trade.Id] = true
+	cascade.trades = append(cascade.trades, trade)
+	cascade.data.Count++
+	cascade.data.Size += trade.Size
+	cascade.data.Notional += notional
+	if trade.Type == proto.Trade_TYPE_NETWORK_CLOSE_OUT_BAD {
+		cascade.data.Type = trade.Type
+	}
+	// the network sells the positions it took over from distressed longs
+	// and buys back the ones of distressed shorts
+	if trade.Seller == networkParty {
+		cascade.data.LongSize += trade.Size
+	} else {
+		cascade.data.ShortSize += trade.Size
+	}
+	if trade.Price < cascade.data.LowPrice {
+		cascade.data.LowPrice = trade.Price
+	}
+	if trade.Price > cascade.data.HighPrice {
+		cascade.data.HighPrice = trade.Price
+	}
+	return nil
+}
+
+// cascade returns the cascade of a market, starting a new one if needed
+func (handler *RektHandler) cascade(marketID string) *rektCascade {
+	cascade, ok := handler.cascades[marketID]
+	if !ok {
+		cascade = &rektCascade{
+			first:   handler.now,
+			parties: make(map[string]bool),
+			seen:    make(map[string]bool),
+			data:    socialevents.RektCascade{MarketID: marketID},
+		}
+		handler.cascades[marketID] = cascade
+	}
+	return cascade
+}
+
+// flush returns the notifications of the cascades older than the window.
+// Cascades without close outs above the thresholds are dropped. A cascade
+// started before the block time was known starts its window now
+func (handler *RektHandler) flush() ([]socialevents.Notification, error) {
+	if handler.now.IsZero() {
+		return nil, nil
+	}
+
+	var expired []*rektCascade
+	for _, cascade := range handler.cascades {
+		if cascade.first.IsZero() {
+			cascade.first = handler.now
+		}
+		if handler.now.Sub(cascade.first) >= handler.settings.Window {
+			expired = append(expired, cascade)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].first.Before(expired[j].first) })
+
+	// a cascade is deleted once all the notifications are built, a failure
+	// keeps it whole for the retry
+	var notifications []socialevents.Notification
+	for _, cascade := range expired {
+		if cascade.data.Count == 0 {
+			continue
+		}
+		var notification socialevents.Notification
+		var err error
+		if cascade.data.Count == 1 {
+			notification, err = socialevents.RektNotification(handler.markets, cascade.trades[0], cascade.data)
+		} else {
+			notification, err = socialevents.RektCascadeNotification(handler.markets, cascade.data)
+		}
+		if err != nil {
+			return notifications, err
+		}
+		// the cascade is flushed by any event, its ID is derived from the close
+		// outs so a replayed cascade is recognised as a duplicate
+		first, last := cascade.trades[0], cascade.trades[len(cascade.trades)-1]
+		notification.EventID = "rekt/" + cascade.data.MarketID + "/" + first.Id + "/" + last.Id
+		notifications = append(notifications, notification)
+	}
+	for _, cascade := range expired {
+		delete(handler.cascades, cascade.data.MarketID)
+	}
+
+	return notifications, nil
+}
@@ -0,0 +1,106 @@
+package eventhandlers
+
+import (
+	"math"
+	"time"
+
+	"github.com/baldator/vega-bot/governance"
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// VoteHandler tallies governance votes. It notifies when a proposal crosses
+// its participation or majority threshold and when its vote is about to close
+type VoteHandler struct {
+	markets *marketcache.Cache
+	tracker *governance.Tracker
+}
+
+// NewVoteHandler creates a governance vote handler
+func NewVoteHandler(markets *marketcache.Cache, tracker *governance.Tracker) *VoteHandler {
+	return &VoteHandler{
+		markets: markets,
+		tracker: tracker,
+	}
+}
+
+// EventTypes returns the bus event types handled by VoteHandler
+func (handler *VoteHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{
+		proto.BusEventType_BUS_EVENT_TYPE_PROPOSAL,
+		proto.BusEventType_BUS_EVENT_TYPE_VOTE,
+		proto.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE,
+		proto.BusEventType_BUS_EVENT_TYPE_NETWORK_PARAMETER,
+	}
+}
+
+// Handle tracks proposals, votes and the governance network parameters and
+// returns the progress and reminder notifications
+func (handler *VoteHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	switch event.Type {
+	case proto.BusEventType_BUS_EVENT_TYPE_PROPOSAL:
+		handler.tracker.Proposal(event.GetProposal())
+	case proto.BusEventType_BUS_EVENT_TYPE_VOTE:
+		return handler.handleVote(ctx, event.GetVote())
+	case proto.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE:
+		return handler.handleTime(time.Unix(0, event.GetTimeUpdate().Timestamp))
+	case proto.BusEventType_BUS_EVENT_TYPE_NETWORK_PARAMETER:
+		if parameter := event.GetNetworkParameter(); parameter != nil {
+			handler.tracker.Parameter(parameter)
+		}
+	}
+	return nil, nil
+}
+
+func (handler *VoteHandler) handleVote(ctx context.Context, vote *proto.Vote) ([]socialevents.Notification, error) {
+	tally, progress, ok, err := handler.tracker.Vote(ctx, vote)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var notifications []socialevents.Notification
+	for _, crossed := range progress {
+		notification, err := socialevents.ProposalProgressNotification(handler.markets, tally.Proposal, handler.details(tally), string(crossed))
+		if err != nil {
+			return notifications, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+func (handler *VoteHandler) handleTime(now time.Time) ([]socialevents.Notification, error) {
+	var notifications []socialevents.Notification
+	for _, tally := range handler.tracker.Due(now) {
+		notification, err := socialevents.ProposalReminderNotification(handler.markets, tally.Proposal, handler.details(tally))
+		if err != nil {
+			return notifications, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// details converts a tally to governance token units
+func (handler *VoteHandler) details(tally governance.Tally) socialevents.ProposalTally {
+	details := socialevents.ProposalTally{
+		Yes:                   tally.Yes,
+		No:                    tally.No,
+		TotalSupply:           tally.TotalSupply,
+		Participation:         tally.Participation(),
+		RequiredParticipation: tally.RequiredParticipation,
+		Majority:              tally.Majority(),
+		RequiredMajority:      tally.RequiredMajority,
+	}
+	if asset := handler.tracker.Asset(); asset != nil {
+		scale := math.Pow(10, float64(asset.Decimals))
+		details.Symbol = asset.Symbol
+		details.Yes /= scale
+		details.No /= scale
+		details.TotalSupply /= scale
+	}
+	return details
+}
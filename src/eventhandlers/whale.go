@@ -0,0 +1,244 @@
+package eventhandlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/orderbook"
+	"github.com/baldator/vega-bot/socialevents"
+	"github.com/baldator/vega-bot/tradevolume"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"golang.org/x/net/context"
+)
+
+// Whale detection strategies
+const (
+	// WhaleStrategyBook compares orders with the resting order book depth
+	WhaleStrategyBook = "book"
+	// WhaleStrategyAverage compares orders and trades with the average traded notional
+	WhaleStrategyAverage = "average"
+	// WhaleStrategyPercentile compares orders and trades with a percentile of the traded notional
+	WhaleStrategyPercentile = "percentile"
+)
+
+// minVolumeSamples is the number of trades needed before the traded volume strategies raise alerts
+const minVolumeSamples = 10
+
+// WhaleSettings configures whale detection
+type WhaleSettings struct {
+	// Strategy is the default detection strategy
+	Strategy string
+	// MarketStrategies overrides the strategy by market ID or code
+	MarketStrategies map[string]string
+	// Threshold is the part of the book depth an order must exceed (book strategy)
+	Threshold float64
+	// DepthRange limits the book depth to this percentage of the mid price, 0 for the whole side (book strategy)
+	DepthRange float64
+	// OrdersThreshold is the minimum number of price levels on both sides (book strategy)
+	OrdersThreshold int
+	// VolumeMultiple is the multiple of the average traded notional to exceed (average strategy)
+	VolumeMultiple float64
+	// VolumePercentile is the traded notional percentile to exceed (percentile strategy)
+	VolumePercentile float64
+	// TradeWindow is the time during which the trades of an aggressive order are aggregated
+	TradeWindow time.Duration
+}
+
+// WhaleHandler notifies large active orders and trades
+type WhaleHandler struct {
+	books    *orderbook.Books
+	volumes  *tradevolume.Tracker
+	markets  *marketcache.Cache
+	settings WhaleSettings
+	isBot    func(partyID string) bool
+	fills    map[string]*fills
+	// lastTrade is the last trade recorded, so a retried trade event is not
+	// counted twice in the traded volume
+	lastTrade string
+}
+
+// NewWhaleHandler creates a whale alert handler. isBot can be nil when the bot blacklist is disabled
+func NewWhaleHandler(books *orderbook.Books, volumes *tradevolume.Tracker, markets *marketcache.Cache, settings WhaleSettings, isBot func(partyID string) bool) *WhaleHandler {
+	if settings.Strategy == "" {
+		settings.Strategy = WhaleStrategyBook
+	}
+	return &WhaleHandler{
+		books:    books,
+		volumes:  volumes,
+		markets:  markets,
+		settings: settings,
+		isBot:    isBot,
+		fills:    make(map[string]*fills),
+	}
+}
+
+// EventTypes returns the bus event types handled by WhaleHandler
+func (handler *WhaleHandler) EventTypes() []proto.BusEventType {
+	return []proto.BusEventType{
+		proto.BusEventType_BUS_EVENT_TYPE_ORDER,
+		proto.BusEventType_BUS_EVENT_TYPE_TRADE,
+		proto.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE,
+	}
+}
+
+// Handle returns a whale notification when an active order or the trades of
+// an aggressive order are large compared to the market. Time updates flush the
+// fills of the orders whose trade window is over
+func (handler *WhaleHandler) Handle(ctx context.Context, event *proto.BusEvent) ([]socialevents.Notification, error) {
+	var notifications []socialevents.Notification
+	var err error
+	var orderID string
+	var now time.Time
+	switch event.Type {
+	case proto.BusEventType_BUS_EVENT_TYPE_ORDER:
+		order := event.GetOrder()
+		if order == nil {
+			return nil, nil
+		}
+		// the order event of an aggressive order follows its trades
+		orderID = order.Id
+		now = time.Unix(0, order.CreatedAt)
+		if order.UpdatedAt > order.CreatedAt {
+			now = time.Unix(0, order.UpdatedAt)
+		}
+		notifications, err = handler.handleOrder(ctx, order)
+	case proto.BusEventType_BUS_EVENT_TYPE_TRADE:
+		trade := event.GetTrade()
+		if trade == nil {
+			return nil, nil
+		}
+		now = time.Unix(0, trade.Timestamp)
+		handler.handleTrade(ctx, trade, now)
+	case proto.BusEventType_BUS_EVENT_TYPE_TIME_UPDATE:
+		now = time.Unix(0, event.GetTimeUpdate().GetTimestamp())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// expired fills stay in handler.fills until every notification is built,
+	// a failure leaves them for the retry which does not add its trade again
+	expired := handler.expiredFills(orderID, now)
+	for _, aggregate := range expired {
+		if !aggregate.isWhale() || handler.ignored(aggregate.trade.PartyID) {
+			continue
+		}
+		notification, err := socialevents.WhaleTradeNotification(handler.markets, aggregate.trade)
+		if err != nil {
+			return notifications, err
+		}
+		notification.EventID = aggregate.eventID()
+		notifications = append(notifications, notification)
+	}
+	for _, aggregate := range expired {
+		delete(handler.fills, aggregate.trade.OrderID)
+	}
+
+	return notifications, nil
+}
+
+func (handler *WhaleHandler) handleOrder(ctx context.Context, order *proto.Order) ([]socialevents.Notification, error) {
+	strategy := handler.strategy(ctx, order.MarketId)
+	var share float64
+	whale := false
+	err := handler.books.Apply(ctx, order, func(book *orderbook.Book) error {
+		if order.Status != proto.Order_STATUS_ACTIVE || strategy != WhaleStrategyBook {
+			return nil
+		}
+		if book.Levels(proto.Side_SIDE_BUY) <= handler.settings.OrdersThreshold || book.Levels(proto.Side_SIDE_SELL) <= handler.settings.OrdersThreshold {
+			return nil
+		}
+		share = book.Share(order, handler.settings.DepthRange)
+		whale = share > handler.settings.Threshold
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == proto.Order_STATUS_ACTIVE && strategy != WhaleStrategyBook {
+		baseline, ok := handler.volumeBaseline(strategy, order.MarketId, time.Unix(0, order.CreatedAt))
+		whale = ok && float64(order.Size*order.Price) > baseline
+	}
+	if !whale || handler.ignored(order.PartyId) {
+		return nil, nil
+	}
+
+	notification, err := socialevents.WhaleNotification(handler.markets, order, share)
+	if err != nil {
+		return nil, err
+	}
+
+	return []socialevents.Notification{notification}, nil
+}
+
+// handleTrade records the trade volume and aggregates the trade with the
+// other fills of its aggressive order
+func (handler *WhaleHandler) handleTrade(ctx context.Context, trade *proto.Trade, at time.Time) {
+	handler.addFill(handler.strategy(ctx, trade.MarketId), trade, at)
+	if trade.Id == handler.lastTrade {
+		return
+	}
+	handler.lastTrade = trade.Id
+	handler.volumes.Add(trade.MarketId, at, float64(trade.Size*trade.Price))
+}
+
+// volumeBaseline returns the notional above which an order or trade is a
+// whale, the highest baseline of the rolling windows. ok is false when the
+// market has not traded enough in any window
+func (handler *WhaleHandler) volumeBaseline(strategy string, marketID string, now time.Time) (baseline float64, ok bool) {
+	for _, window := range handler.volumes.Windows() {
+		var windowBaseline float64
+		var count int
+		switch strategy {
+		case WhaleStrategyAverage:
+			var average float64
+			average, count = handler.volumes.Average(marketID, window, now)
+			windowBaseline = average * handler.settings.VolumeMultiple
+		case WhaleStrategyPercentile:
+			windowBaseline, count = handler.volumes.Percentile(marketID, handler.settings.VolumePercentile, window, now)
+		default:
+			return 0, false
+		}
+
+		if count < minVolumeSamples {
+			continue
+		}
+		if !ok || windowBaseline > baseline {
+			baseline = windowBaseline
+		}
+		ok = true
+	}
+	return baseline, ok
+}
+
+// strategy returns the detection strategy of a market
+func (handler *WhaleHandler) strategy(ctx context.Context, marketID string) string {
+	if strategy, ok := handler.settings.MarketStrategies[marketID]; ok {
+		return strategy
+	}
+	if len(handler.settings.MarketStrategies) > 0 {
+		info, err := handler.markets.Info(ctx, marketID)
+		if err == nil {
+			if strategy, ok := handler.settings.MarketStrategies[info.Code]; ok {
+				return strategy
+			}
+		}
+	}
+	return handler.settings.Strategy
+}
+
+// ignored tells if a party is in the bot blacklist
+func (handler *WhaleHandler) ignored(partyID string) bool {
+	if handler.isBot == nil {
+		return false
+	}
+	if handler.isBot(partyID) {
+		log.Printf("Party id %s is in the blacklist. Ignoring...", partyID)
+		return true
+	}
+	log.Printf("Party id %s is not in the blacklist. Continuing...", partyID)
+	return false
+}
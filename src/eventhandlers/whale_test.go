@@ -0,0 +1,55 @@
+package eventhandlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baldator/vega-bot/tradevolume"
+)
+
+func TestVolumeBaseline(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	// recent adds trades of notional value within the last hour, old within the day
+	type volumes struct {
+		recent, recentValue int
+		old, oldValue       int
+	}
+	tests := []struct {
+		name     string
+		strategy string
+		volumes  volumes
+		want     float64
+		wantOK   bool
+	}{
+		{"no trades", WhaleStrategyAverage, volumes{}, 0, false},
+		{"too few trades in every window", WhaleStrategyAverage, volumes{recent: 5, recentValue: 100, old: 4, oldValue: 100}, 0, false},
+		{"only the 24h window has enough trades", WhaleStrategyAverage, volumes{recent: 5, recentValue: 100, old: 5, oldValue: 300}, 2000, true},
+		{"the 1h window has the highest average", WhaleStrategyAverage, volumes{recent: 10, recentValue: 400, old: 30, oldValue: 100}, 4000, true},
+		{"the 24h window has the highest average", WhaleStrategyAverage, volumes{recent: 10, recentValue: 100, old: 10, oldValue: 500}, 3000, true},
+		{"percentile of the highest window", WhaleStrategyPercentile, volumes{recent: 10, recentValue: 400, old: 90, oldValue: 100}, 400, true},
+		{"book strategy has no volume baseline", WhaleStrategyBook, volumes{recent: 20, recentValue: 100}, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := tradevolume.NewTracker(time.Hour, 24*time.Hour)
+			for i := 0; i < test.volumes.old; i++ {
+				tracker.Add("market", now.Add(-2*time.Hour), float64(test.volumes.oldValue))
+			}
+			for i := 0; i < test.volumes.recent; i++ {
+				tracker.Add("market", now.Add(-time.Minute), float64(test.volumes.recentValue))
+			}
+			handler := &WhaleHandler{
+				volumes: tracker,
+				settings: WhaleSettings{
+					VolumeMultiple:   10,
+					VolumePercentile: 90,
+				},
+			}
+
+			baseline, ok := handler.volumeBaseline(test.strategy, "market", now)
+			if baseline != test.want || ok != test.wantOK {
+				t.Errorf("volumeBaseline = %v, %v, want %v, %v", baseline, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package eventhandlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/baldator/vega-bot/orderbook"
+	"github.com/baldator/vega-bot/socialevents"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+)
+
+// fills aggregates the trades of an aggressive order
+type fills struct {
+	trade          socialevents.WhaleTrade
+	first          time.Time
+	firstTradeID   string
+	lastTradeID    string
+	trades         map[string]bool
+	notional       float64
+	baseline       float64
+	counterparties map[string]bool
+}
+
+// aggressorOrder returns the aggressive order and party of a trade. ok is
+// false for trades without aggressor, like auction uncrossing and close outs
+func aggressorOrder(trade *proto.Trade) (orderID string, partyID string, counterparty string, ok bool) {
+	if trade.Type == proto.Trade_TYPE_NETWORK_CLOSE_OUT_GOOD || trade.Type == proto.Trade_TYPE_NETWORK_CLOSE_OUT_BAD {
+		return "", "", "", false
+	}
+	switch trade.Aggressor {
+	case proto.Side_SIDE_BUY:
+		return trade.BuyOrder, trade.Buyer, trade.Seller, true
+	case proto.Side_SIDE_SELL:
+		return trade.SellOrder, trade.Seller, trade.Buyer, true
+	}
+	return "", "", "", false
+}
+
+// addFill adds a trade to the fills of its aggressive order. The notional the
+// fills are compared with is taken when the first trade is seen. A trade
+// already added is ignored
+func (handler *WhaleHandler) addFill(strategy string, trade *proto.Trade, at time.Time) {
+	orderID, partyID, counterparty, ok := aggressorOrder(trade)
+	if !ok {
+		return
+	}
+
+	aggregate, ok := handler.fills[orderID]
+	if !ok {
+		aggregate = &fills{
+			trade: socialevents.WhaleTrade{
+				MarketID: trade.MarketId,
+				OrderID:  orderID,
+				PartyID:  partyID,
+				Side:     trade.Aggressor,
+			},
+			first:          at,
+			firstTradeID:   trade.Id,
+			trades:         make(map[string]bool),
+			counterparties: make(map[string]bool),
+		}
+		// the passive side of the book before the order traded
+		passive := proto.Side_SIDE_SELL
+		if trade.Aggressor == proto.Side_SIDE_SELL {
+			passive = proto.Side_SIDE_BUY
+		}
+		handler.books.View(trade.MarketId, func(book *orderbook.Book) {
+			aggregate.trade.PreTradeMid, _ = book.Mid()
+			if strategy == WhaleStrategyBook {
+				aggregate.baseline = float64(book.Depth(passive, handler.settings.DepthRange)) * handler.settings.Threshold
+			}
+		})
+		if strategy != WhaleStrategyBook {
+			aggregate.baseline, _ = handler.volumeBaseline(strategy, trade.MarketId, at)
+		}
+		handler.fills[orderID] = aggregate
+	}
+
+	if aggregate.trades[trade.Id] {
+		return
+	}
+	aggregate.trades[trade.Id] = true
+	aggregate.lastTradeID = trade.Id
+	aggregate.notional += float64(trade.Size * trade.Price)
+	aggregate.trade.Size += trade.Size
+	aggregate.trade.LastPrice = trade.Price
+	aggregate.trade.VWAP = aggregate.notional / float64(aggregate.trade.Size)
+	aggregate.counterparties[counterparty] = true
+	aggregate.trade.Counterparties = len(aggregate.counterparties)
+}
+
+// expiredFills returns the fills of orderID, if any, and the fills older than
+// the trade window
+func (handler *WhaleHandler) expiredFills(orderID string, now time.Time) []*fills {
+	var expired []*fills
+	for id, aggregate := range handler.fills {
+		if id == orderID || now.Sub(aggregate.first) >= handler.settings.TradeWindow {
+			expired = append(expired, aggregate)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].first.Before(expired[j].first) })
+	return expired
+}
+
+// eventID identifies the fills from their trades, whatever event flushed them
+func (aggregate *fills) eventID() string {
+	return "whale-trade/" + aggregate.trade.OrderID + "/" + aggregate.firstTradeID + "/" + aggregate.lastTradeID
+}
+
+// isWhale tells if the fills of an order are large compared to the market
+func (aggregate *fills) isWhale() bool {
+	return aggregate.baseline > 0 && aggregate.notional > aggregate.baseline
+}
@@ -11,6 +11,7 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.9.0
 	github.com/vegaprotocol/api-clients v0.31.0
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/net v0.0.0-20210220033124-5f55cee0dc0d
 	google.golang.org/grpc v1.35.0
 )
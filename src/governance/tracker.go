@@ -0,0 +1,368 @@
+package governance
+
+import (
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+// voteAssetParameter is the network parameter holding the governance token
+const voteAssetParameter = "governance.vote.asset"
+
+// Progress is a threshold crossed by the tally of a proposal
+type Progress string
+
+// Tally progresses
+const (
+	ParticipationReached Progress = "participation reached"
+	ParticipationLost    Progress = "participation lost"
+	MajorityReached      Progress = "majority reached"
+	MajorityLost         Progress = "majority lost"
+)
+
+// Tally is the vote count of an open proposal, weighted by the governance
+// token balance of the voters. Amounts are expressed in token decimals, as
+// floats because the supply of an 18 decimals token exceeds 64 bits
+type Tally struct {
+	Proposal              *proto.Proposal
+	Yes                   float64
+	No                    float64
+	TotalSupply           float64
+	RequiredParticipation float64
+	RequiredMajority      float64
+
+	votes         map[string]vote
+	participation bool
+	majority      bool
+	reminded      bool
+}
+
+type vote struct {
+	yes    bool
+	weight float64
+}
+
+// Participation returns the share of the token supply that voted
+func (tally Tally) Participation() float64 {
+	if tally.TotalSupply == 0 {
+		return 0
+	}
+	return (tally.Yes + tally.No) / tally.TotalSupply
+}
+
+// Majority returns the share of the votes in favour of the proposal
+func (tally Tally) Majority() float64 {
+	if tally.Yes+tally.No == 0 {
+		return 0
+	}
+	return tally.Yes / (tally.Yes + tally.No)
+}
+
+// ParticipationReached tells if enough tokens voted for the proposal to be valid
+func (tally Tally) ParticipationReached() bool {
+	return tally.TotalSupply > 0 && tally.Participation() >= tally.RequiredParticipation
+}
+
+// MajorityReached tells if enough votes are in favour for the proposal to pass
+func (tally Tally) MajorityReached() bool {
+	return tally.Yes+tally.No > 0 && tally.Majority() >= tally.RequiredMajority
+}
+
+// Closing returns the end of the vote
+func (tally Tally) Closing() time.Time {
+	return time.Unix(tally.Proposal.GetTerms().GetClosingTimestamp(), 0)
+}
+
+// Tracker keeps the tallies of the open proposals. It is safe for concurrent use
+type Tracker struct {
+	dataClient api.TradingDataServiceClient
+	reminder   time.Duration
+	mu         sync.Mutex
+	parameters map[string]string
+	asset      *proto.Asset
+	tallies    map[string]*Tally
+	loaded     bool
+}
+
+// NewTracker creates a tracker. A reminder is due reminder before a proposal closes
+func NewTracker(dataClient api.TradingDataServiceClient, reminder time.Duration) *Tracker {
+	return &Tracker{
+		dataClient: dataClient,
+		reminder:   reminder,
+		parameters: make(map[string]string),
+		tallies:    make(map[string]*Tally),
+	}
+}
+
+// Load reads the governance network parameters and the votes already cast on
+// the open proposals. Thresholds already crossed and reminders already due
+// are not reported again. When it fails, loading is retried on the next vote
+func (tracker *Tracker) Load(ctx context.Context, now time.Time) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	return tracker.load(ctx, now)
+}
+
+// load implements Load. The lock must be held
+func (tracker *Tracker) load(ctx context.Context, now time.Time) error {
+	parameters, err := tracker.dataClient.NetworkParameters(ctx, &api.NetworkParametersRequest{})
+	if err != nil {
+		return errors.Wrap(err, "could not get network parameters")
+	}
+
+	for _, parameter := range parameters.NetworkParameters {
+		tracker.parameters[parameter.Key] = parameter.Value
+	}
+
+	assetID := tracker.parameters[voteAssetParameter]
+	if assetID == "" {
+		return errors.New("network parameter " + voteAssetParameter + " not found")
+	}
+	asset, err := tracker.dataClient.AssetByID(ctx, &api.AssetByIDRequest{Id: assetID})
+	if err != nil {
+		return errors.Wrap(err, "could not get governance asset "+assetID)
+	}
+	tracker.asset = asset.Asset
+
+	proposals, err := tracker.dataClient.GetProposals(ctx, &api.GetProposalsRequest{
+		SelectInState: &api.OptionalProposalState{Value: proto.Proposal_STATE_OPEN},
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not get open proposals")
+	}
+	for _, data := range proposals.Data {
+		tally := tracker.track(data.Proposal)
+		for _, votes := range [][]*proto.Vote{data.Yes, data.No} {
+			for _, vote := range votes {
+				err = tracker.count(ctx, tally, vote)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		tally.participation = tally.ParticipationReached()
+		tally.majority = tally.MajorityReached()
+		tally.reminded = !now.Before(tally.Closing().Add(-tracker.reminder))
+	}
+
+	tracker.loaded = true
+	return nil
+}
+
+// Asset returns the governance token, nil until the tracker is loaded
+func (tracker *Tracker) Asset() *proto.Asset {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	return tracker.asset
+}
+
+// Parameter records a network parameter update. New thresholds apply to the
+// proposals tracked afterwards, a new governance token reloads the tracker
+func (tracker *Tracker) Parameter(parameter *proto.NetworkParameter) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.parameters[parameter.Key] = parameter.Value
+	if parameter.Key == voteAssetParameter && (tracker.asset == nil || tracker.asset.Id != parameter.Value) {
+		tracker.loaded = false
+	}
+}
+
+// Proposal records a proposal update. Proposals are tracked while they are open
+func (tracker *Tracker) Proposal(proposal *proto.Proposal) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if proposal.State != proto.Proposal_STATE_OPEN {
+		delete(tracker.tallies, proposal.Id)
+		return
+	}
+	tracker.track(proposal)
+}
+
+// Vote counts a vote, replacing the previous vote of the party on the same
+// proposal, and returns the updated tally with the thresholds it crossed.
+// ok is false when the proposal is not open
+func (tracker *Tracker) Vote(ctx context.Context, vote *proto.Vote) (tally Tally, progress []Progress, ok bool, err error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if !tracker.loaded {
+		tracker.tallies = make(map[string]*Tally)
+		err = tracker.load(ctx, time.Unix(0, vote.Timestamp))
+		if err != nil {
+			return Tally{}, nil, false, err
+		}
+	}
+
+	current, ok := tracker.tallies[vote.ProposalId]
+	if !ok {
+		response, err := tracker.dataClient.GetProposalByID(ctx, &api.GetProposalByIDRequest{ProposalId: vote.ProposalId})
+		if err != nil {
+			return Tally{}, nil, false, errors.Wrap(err, "could not get proposal "+vote.ProposalId)
+		}
+		proposal := response.GetData().GetProposal()
+		if proposal == nil || proposal.State != proto.Proposal_STATE_OPEN {
+			return Tally{}, nil, false, nil
+		}
+		current = tracker.track(proposal)
+	}
+
+	err = tracker.count(ctx, current, vote)
+	if err != nil {
+		return Tally{}, nil, false, err
+	}
+
+	if reached := current.ParticipationReached(); reached != current.participation {
+		current.participation = reached
+		if reached {
+			progress = append(progress, ParticipationReached)
+		} else {
+			progress = append(progress, ParticipationLost)
+		}
+	}
+	if reached := current.MajorityReached(); reached != current.majority {
+		current.majority = reached
+		if reached {
+			progress = append(progress, MajorityReached)
+		} else {
+			progress = append(progress, MajorityLost)
+		}
+	}
+
+	return *current, progress, true, nil
+}
+
+// Due returns the tallies of the proposals closing within the reminder
+// duration that were not reminded yet, and marks them as reminded
+func (tracker *Tracker) Due(now time.Time) []Tally {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	var due []Tally
+	for _, tally := range tracker.tallies {
+		closing := tally.Closing()
+		if tally.reminded || now.Before(closing.Add(-tracker.reminder)) || !now.Before(closing) {
+			continue
+		}
+		tally.reminded = true
+		due = append(due, *tally)
+	}
+	return due
+}
+
+// track starts tracking a proposal, or updates the tracked one. The lock must be held
+func (tracker *Tracker) track(proposal *proto.Proposal) *Tally {
+	if tally, ok := tracker.tallies[proposal.Id]; ok {
+		tally.Proposal = proposal
+		return tally
+	}
+
+	kind := proposalKind(proposal.GetTerms())
+	tally := &Tally{
+		Proposal:              proposal,
+		TotalSupply:           tracker.totalSupply(),
+		RequiredParticipation: tracker.threshold("governance.proposal." + kind + ".requiredParticipation"),
+		RequiredMajority:      tracker.threshold("governance.proposal." + kind + ".requiredMajority"),
+		votes:                 make(map[string]vote),
+	}
+	tracker.tallies[proposal.Id] = tally
+	return tally
+}
+
+// count adds a vote to a tally, weighted by the current balance of the
+// party. The lock must be held
+func (tracker *Tracker) count(ctx context.Context, tally *Tally, proposalVote *proto.Vote) error {
+	weight, err := tracker.balance(ctx, proposalVote.PartyId)
+	if err != nil {
+		return err
+	}
+
+	if previous, ok := tally.votes[proposalVote.PartyId]; ok {
+		if previous.yes {
+			tally.Yes -= previous.weight
+		} else {
+			tally.No -= previous.weight
+		}
+	}
+
+	current := vote{yes: proposalVote.Value == proto.Vote_VALUE_YES, weight: weight}
+	tally.votes[proposalVote.PartyId] = current
+	if current.yes {
+		tally.Yes += weight
+	} else {
+		tally.No += weight
+	}
+	return nil
+}
+
+// balance returns the governance token balance of a party. The lock must be held
+func (tracker *Tracker) balance(ctx context.Context, partyID string) (float64, error) {
+	if tracker.asset == nil {
+		return 0, nil
+	}
+
+	response, err := tracker.dataClient.PartyAccounts(ctx, &api.PartyAccountsRequest{
+		PartyId: partyID,
+		Asset:   tracker.asset.Id,
+		Type:    proto.AccountType_ACCOUNT_TYPE_GENERAL,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "could not get accounts of party "+partyID)
+	}
+
+	var balance float64
+	for _, account := range response.Accounts {
+		balance += float64(account.Balance)
+	}
+	return balance, nil
+}
+
+// totalSupply returns the supply of the governance token. The lock must be held
+func (tracker *Tracker) totalSupply() float64 {
+	if tracker.asset == nil {
+		return 0
+	}
+	supply, ok := new(big.Float).SetString(tracker.asset.TotalSupply)
+	if !ok {
+		log.Printf("Invalid governance asset total supply %q\n", tracker.asset.TotalSupply)
+		return 0
+	}
+	value, _ := supply.Float64()
+	return value
+}
+
+// threshold returns a ratio network parameter. The lock must be held
+func (tracker *Tracker) threshold(key string) float64 {
+	value, err := strconv.ParseFloat(tracker.parameters[key], 64)
+	if err != nil {
+		log.Printf("Invalid network parameter %s: %q\n", key, tracker.parameters[key])
+		return 0
+	}
+	return value
+}
+
+// proposalKind returns the name of the proposal type used in the governance
+// network parameters
+func proposalKind(terms *proto.ProposalTerms) string {
+	switch {
+	case terms.GetNewMarket() != nil:
+		return "market"
+	case terms.GetUpdateMarket() != nil:
+		return "updateMarket"
+	case terms.GetUpdateNetworkParameter() != nil:
+		return "updateNetParam"
+	case terms.GetNewAsset() != nil:
+		return "asset"
+	}
+	return ""
+}
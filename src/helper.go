@@ -3,13 +3,17 @@ package main
 import (
 	"bufio"
 	"encoding/json"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
+	"github.com/baldator/vega-bot/social"
+	"github.com/baldator/vega-bot/socialevents"
+	"github.com/baldator/vega-bot/state"
+
 	"github.com/getsentry/sentry-go"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
@@ -18,31 +22,50 @@ import (
 )
 
 const (
-	ethereumConfigDir  = "data"
-	ethereumConfigFile = "ethereum.conf"
-	uptimeConfigFile   = "uptime.conf"
-	botBlacklistFile   = "bots.conf"
+	ethereumConfigDir = "data"
+	stateFile         = "state.db"
+	botBlacklistFile  = "bots.conf"
 )
 
 var botBlacklist []string
 
-func vegaNetworkReset(dataClient api.TradingDataServiceClient) (bool, string, error) {
+// openStateStore opens the state store in the data directory, importing the
+// state files of previous versions
+func openStateStore() (state.StateStore, error) {
+	err := os.MkdirAll(ethereumConfigDir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := state.NewBoltStore(filepath.Join(ethereumConfigDir, stateFile))
+	if err != nil {
+		return nil, err
+	}
+
+	err = state.MigrateFiles(store, ethereumConfigDir)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func vegaNetworkReset(dataClient api.TradingDataServiceClient, store state.StateStore) (bool, string, error) {
 	currentUptime, err := readVegaUptime(dataClient)
 	if err != nil {
 		return false, "", err
 	}
 
-	previousUptime, err := readPreviousUptimeConfig(dataClient)
+	previousUptime, ok, err := store.Uptime()
 	if err != nil {
 		return false, "", err
 	}
+	if !ok {
+		return false, "", store.SetUptime(currentUptime)
+	}
 
 	if previousUptime != currentUptime {
-		err = writeUptimeConfig(currentUptime)
-		if err != nil {
-			return false, "", err
-		}
-
 		return true, currentUptime, nil
 	}
 
@@ -78,128 +101,69 @@ func readVegaUptime(dataClient api.TradingDataServiceClient) (string, error) {
 	return uptime, nil
 }
 
-func readPreviousUptimeConfig(dataClient api.TradingDataServiceClient) (string, error) {
-	fullPath := ethereumConfigDir + "/" + uptimeConfigFile
-	fileExist, err := exists(fullPath)
-
-	if !fileExist {
-		uptime, err := readVegaUptime(dataClient)
-		if err != nil {
-			return "", err
-		}
-		err = writeUptimeConfig(uptime)
-		if err != nil {
-			return "", err
-		}
-
-	}
-
-	config, err := ioutil.ReadFile(fullPath)
-	if err != nil {
-		return "", err
-	}
-
-	return string(config), nil
-}
-
-func writeUptimeConfig(uptime string) error {
-	if _, err := os.Stat(ethereumConfigDir); os.IsNotExist(err) {
-		log.Println("Creating directory")
-		os.Mkdir(ethereumConfigDir, os.ModePerm)
-	}
-
-	fullPath := ethereumConfigDir + "/" + uptimeConfigFile
-	ioutil.WriteFile(fullPath, []byte(uptime), 0644)
-	return nil
-}
-
-func writeEthereumConfig(ethereumConfig *proto.NetworkParameter) error {
-	if _, err := os.Stat(ethereumConfigDir); os.IsNotExist(err) {
-		log.Println("Creating directory")
-		os.Mkdir(ethereumConfigDir, os.ModePerm)
-	}
-	configContent, err := json.MarshalIndent(ethereumConfig, "", " ")
+func readPreviousEthereumConfig(dataClient api.TradingDataServiceClient, store state.StateStore) (*proto.NetworkParameter, error) {
+	previousEthereumConfig, err := store.EthereumConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	fullPath := ethereumConfigDir + "/" + ethereumConfigFile
-	ioutil.WriteFile(fullPath, configContent, 0644)
-	return nil
-}
-
-func readPreviousEthereumConfig(dataClient api.TradingDataServiceClient) (*proto.NetworkParameter, error) {
-	fullPath := ethereumConfigDir + "/" + ethereumConfigFile
-	log.Println("Check if file " + fullPath + " exists")
-	fileExist, err := exists(fullPath)
-
-	if !fileExist {
-		config, err := readEthereumConfig(dataClient)
-		if err != nil {
-			return nil, err
-		}
-		err = writeEthereumConfig(config)
-		if err != nil {
-			return nil, err
-		}
-
+	if previousEthereumConfig != nil {
+		return previousEthereumConfig, nil
 	}
 
-	log.Println("Reading file " + fullPath)
-	var currentEthereumConfig *proto.NetworkParameter
-	config, err := ioutil.ReadFile(fullPath)
+	log.Println("No Ethereum config stored, reading it from the network")
+	config, err := readEthereumConfig(dataClient)
 	if err != nil {
 		return nil, err
 	}
-
-	err = json.Unmarshal(config, &currentEthereumConfig)
+	err = store.SetEthereumConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
-	return currentEthereumConfig, nil
+	return config, nil
 }
 
-func exists(path string) (bool, error) {
-	_, err := os.Stat(path)
-	if err == nil {
-		return true, nil
-	}
-	if os.IsNotExist(err) {
-		return false, nil
+// validEthereumConfig tells if a blockchains.ethereumConfig network parameter
+// holds a valid configuration
+func validEthereumConfig(parameter *proto.NetworkParameter) bool {
+	if parameter == nil {
+		return false
 	}
-	return false, err
+	_, err := socialevents.ParseEthereumConfig(parameter.Value)
+	return err == nil
 }
 
-func getMarketValue(dataClient api.TradingDataServiceClient, marketID string, side proto.Side, whaleOrdersThreshold int) (uint64, bool, error) {
-	requestMarketDepth := api.MarketDepthRequest{MarketId: marketID}
-	marketDepthObject, err := dataClient.MarketDepth(context.Background(), &requestMarketDepth)
-	if err != nil {
-		return 0, false, err
-	}
-
-	var marketValue uint64
-	marketValue = 0
-	marketOrdersBuy := len(marketDepthObject.Buy)
-	marketOrderSell := len(marketDepthObject.Sell)
-	marketOrdersFlag := false
-	if marketOrdersBuy > whaleOrdersThreshold && marketOrderSell > whaleOrdersThreshold {
-		marketOrdersFlag = true
+func initializeTransports(conf ConfigVars) ([]social.Transport, error) {
+	var transports []social.Transport
+	postToSocials := map[string]bool{
+		"discord":  conf.SocialDiscordEnabled,
+		"twitter":  conf.SocialTwitterEnabled,
+		"telegram": conf.SocialTelegramEnabled,
+		"slack":    conf.SocialSlackEnabled,
+	}
+	for _, platform := range []string{"discord", "twitter", "telegram", "slack"} {
+		if postToSocials[platform] {
+			transports = append(transports, social.NewPostToSocials(conf.SocialServiceURL, conf.SocialServiceKey, conf.SocialServiceSecret, platform))
+		}
 	}
-
-	if side == proto.Side_SIDE_BUY {
-		for _, val := range marketDepthObject.Buy {
-			marketValue = marketValue + val.Volume*val.Price
+	if len(transports) > 0 {
+		err := social.CheckPostToSocialsStatus(conf.SocialServiceURL)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	if side == proto.Side_SIDE_SELL {
-		for _, val := range marketDepthObject.Sell {
-			marketValue = marketValue + val.Volume*val.Price
-		}
+	if conf.SocialDiscordWebhookURL != "" {
+		transports = append(transports, social.NewDiscordWebhook(conf.SocialDiscordWebhookURL))
+	}
+	if conf.SocialSlackWebhookURL != "" {
+		transports = append(transports, social.NewSlackWebhook(conf.SocialSlackWebhookURL))
+	}
+	if conf.SocialTelegramBotToken != "" && conf.SocialTelegramChatID != "" {
+		transports = append(transports, social.NewTelegramBot(conf.SocialTelegramBotToken, conf.SocialTelegramChatID))
 	}
 
-	return marketValue, marketOrdersFlag, nil
+	return transports, nil
 }
 
 func initializeSentry(sentryDsn string) {
@@ -224,6 +188,14 @@ func initializePrometheus(port int) {
 	}()
 }
 
+// captureError reports an error that does not stop the bot
+func captureError(err error, sentryEnabled bool) {
+	if sentryEnabled {
+		sentry.CaptureException(err)
+	}
+	log.Println(err)
+}
+
 func logError(err error, sentryEnabled bool) {
 	if sentryEnabled {
 		sentry.CaptureException(err)
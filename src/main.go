@@ -2,18 +2,24 @@ package main
 
 import (
 	"encoding/json"
-	"io"
 	"log"
 	"time"
 
+	"github.com/baldator/vega-bot/auctions"
+	"github.com/baldator/vega-bot/errorpolicy"
+	"github.com/baldator/vega-bot/eventhandlers"
+	"github.com/baldator/vega-bot/governance"
+	"github.com/baldator/vega-bot/marketcache"
+	"github.com/baldator/vega-bot/orderbook"
 	"github.com/baldator/vega-bot/social"
 	"github.com/baldator/vega-bot/socialevents"
+	"github.com/baldator/vega-bot/tradevolume"
+	"github.com/baldator/vega-bot/vegaclient"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
 	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
 	"golang.org/x/net/context"
-	"google.golang.org/grpc"
 )
 
 func main() {
@@ -35,6 +41,11 @@ func main() {
 		initializeBots()
 	}
 
+	err = socialevents.LoadTemplates(conf.TemplatesDir, conf.ExplorerURL, conf.GovernanceURL)
+	if err != nil {
+		log.Fatal("Failed to load templates: ", err)
+	}
+
 	func() {
 		if conf.SentryEnabled {
 			defer sentry.Recover()
@@ -48,68 +59,70 @@ func main() {
 
 		}
 
-		socialPost, err := social.NewSocialChannel(conf.SocialServiceURL, conf.SocialServiceKey, conf.SocialServiceSecret, conf.SocialTwitterEnabled, conf.SocialDiscordEnabled, conf.SocialSlackEnabled, conf.SocialTelegramEnabled)
+		store, err := openStateStore()
+		if err != nil {
+			logError(err, conf.SentryEnabled)
+		}
+		defer store.Close()
+
+		transports, err := initializeTransports(conf)
+		if err != nil {
+			logError(err, conf.SentryEnabled)
+		}
+
+		socialPost, err := social.NewSocialChannel(transports, ethereumConfigDir, conf.OutboxMaxAttempts, conf.OutboxRetryDelay, social.NewDeduplicator(store, conf.SocialDedupTTL))
+		if err != nil {
+			logError(err, conf.SentryEnabled)
+		}
+		for platform, perMinute := range conf.SocialRateLimits {
+			socialPost.SetRateLimit(platform, perMinute, conf.SocialRateBurst)
+		}
+
+		nodePool, err := vegaclient.NewNodePool(conf.NodeURLs())
 		if err != nil {
 			logError(err, conf.SentryEnabled)
 		}
 
-		conn, err := grpc.Dial(conf.GrpcNodeURL, grpc.WithInsecure(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(256<<22)))
+		conn, err := vegaclient.Dial(nodePool)
 		if err != nil {
 			logError(err, conf.SentryEnabled)
 		}
 		defer conn.Close()
 
 		dataClient := api.NewTradingDataServiceClient(conn)
-		eventType := []proto.BusEventType{}
 
 		if conf.VegaNetworkParametersEnabled == true {
 			go func() {
 				for {
-					flagReset, uptime, err := vegaNetworkReset(dataClient)
+					flagReset, uptime, err := vegaNetworkReset(dataClient, store)
 					if err != nil {
-						logError(err, conf.SentryEnabled)
+						captureError(err, conf.SentryEnabled)
 					}
 					if flagReset {
-						message, err := socialevents.NetworkResetNotification(uptime)
+						notification, err := socialevents.NetworkResetNotification(uptime)
 						if err != nil {
-							logError(err, conf.SentryEnabled)
+							captureError(err, conf.SentryEnabled)
 						}
-						if message != "" {
-							socialPost.SendMessage(message)
-
-							// reinitialize network parameters
-							err = writeUptimeConfig(uptime)
+						if notification.Message != "" {
+							err = socialPost.Send(notification)
 							if err != nil {
-								logError(err, conf.SentryEnabled)
+								captureError(err, conf.SentryEnabled)
 							}
-						} else {
-							log.Println("Network start time didn't change since last run")
+						}
+
+						// reinitialize network parameters
+						err = store.SetUptime(uptime)
+						if err != nil {
+							captureError(err, conf.SentryEnabled)
 						}
 					}
 					time.Sleep(60 * time.Second)
 				}
 			}()
 		}
-		if conf.VegaLossSocializationEnabled == true {
-			eventType = append(eventType, proto.BusEventType_BUS_EVENT_TYPE_LOSS_SOCIALIZATION)
-		}
-		if conf.VegaAuctionsEnabled == true {
-			eventType = append(eventType, proto.BusEventType_BUS_EVENT_TYPE_AUCTION)
-		}
-		if conf.VegaProposalsEnabled == true {
-			eventType = append(eventType, proto.BusEventType_BUS_EVENT_TYPE_PROPOSAL)
-		}
-		if conf.VegaTradesEnabled == true {
-			eventType = append(eventType, proto.BusEventType_BUS_EVENT_TYPE_TRADE)
-		}
-		if conf.VegaOrdersEnabled == true {
-			eventType = append(eventType, proto.BusEventType_BUS_EVENT_TYPE_ORDER)
-		}
 
-		events, err := dataClient.ObserveEventBus(context.Background())
-
-		// check if network ID changed since last run
-		previousEthereumConfig, err := readPreviousEthereumConfig(dataClient)
+		// check if the Ethereum config changed since last run
+		previousEthereumConfig, err := readPreviousEthereumConfig(dataClient, store)
 		if err != nil {
 			logError(err, conf.SentryEnabled)
 		}
@@ -119,141 +132,164 @@ func main() {
 			logError(err, conf.SentryEnabled)
 		}
 
-		message := socialevents.NetworkParametesNotification(dataClient, currentEthereumConfig, previousEthereumConfig)
-		if message != "" {
-			socialPost.SendMessage(message)
+		notification, err := socialevents.NetworkParametesNotification(currentEthereumConfig, previousEthereumConfig)
+		if err != nil {
+			captureError(err, conf.SentryEnabled)
+		}
+		// an invalid stored config is replaced by a valid current one, an
+		// invalid current config keeps the stored one as the reference
+		storedInvalid := err != nil && previousEthereumConfig != nil && !validEthereumConfig(previousEthereumConfig) && validEthereumConfig(currentEthereumConfig)
+		if notification.Message != "" || storedInvalid {
+			if notification.Message != "" {
+				err = socialPost.Send(notification)
+				if err != nil {
+					captureError(err, conf.SentryEnabled)
+				}
+			}
 
 			// reinitialize network parameters
-			err = writeEthereumConfig(currentEthereumConfig)
+			err = store.SetEthereumConfig(currentEthereumConfig)
 			if err != nil {
 				logError(err, conf.SentryEnabled)
 			}
-		} else {
-			log.Println("Network ID didn't change since last run")
+		} else if err == nil {
+			log.Println("Ethereum config didn't change since last run")
+		}
+		referenceEthereumConfig := currentEthereumConfig
+		if !validEthereumConfig(currentEthereumConfig) {
+			referenceEthereumConfig = previousEthereumConfig
 		}
 
-		done := make(chan bool)
-		go func() {
-			for {
-				resp, err := events.Recv()
+		policy := errorpolicy.NewPolicy(conf.ErrorMaxRetries, conf.ErrorRetryDelay, conf.ErrorMaxRetryTime, conf.SentryEnabled)
+		markets := marketcache.NewCache(dataClient)
+		err = markets.Warm(context.Background())
+		if err != nil {
+			captureError(err, conf.SentryEnabled)
+		}
 
-				if err == io.EOF {
-					close(done)
-					return
-				}
+		books := orderbook.NewBooks(dataClient)
 
+		registry := eventhandlers.NewRegistry(policy)
+		registry.Register(eventhandlers.NewMarketCacheHandler(markets))
+		if conf.VegaMarketCreatedEnabled || conf.VegaMarketUpdatedEnabled {
+			registry.Register(eventhandlers.NewMarketHandler(markets, conf.VegaMarketCreatedEnabled, conf.VegaMarketUpdatedEnabled))
+		}
+		if conf.VegaLossSocializationEnabled == true {
+			registry.Register(eventhandlers.NewLossSocializationHandler(markets))
+		}
+		if conf.VegaAuctionsEnabled == true {
+			tracker, err := auctions.NewTracker(store)
+			if err != nil {
+				logError(err, conf.SentryEnabled)
+			}
+			err = tracker.Reconcile(context.Background(), dataClient)
+			if err != nil {
+				captureError(err, conf.SentryEnabled)
+			}
+			registry.Register(eventhandlers.NewAuctionHandler(dataClient, markets, tracker, conf.VegaAuctionsExtendEnabled))
+		}
+		if conf.VegaProposalsEnabled == true {
+			registry.Register(eventhandlers.NewProposalHandler(markets))
+		}
+		if conf.VegaVotesEnabled == true {
+			proposals := governance.NewTracker(dataClient, conf.ProposalReminderBefore)
+			err = proposals.Load(context.Background(), time.Now())
+			if err != nil {
+				captureError(err, conf.SentryEnabled)
+			}
+			registry.Register(eventhandlers.NewVoteHandler(markets, proposals))
+		}
+		if conf.VegaTradesEnabled == true {
+			registry.Register(eventhandlers.NewRektHandler(dataClient, markets, eventhandlers.RektSettings{
+				Window:          conf.RektCascadeWindow,
+				BadMinNotional:  conf.RektMinNotional,
+				GoodEnabled:     conf.RektCloseOutGoodEnabled,
+				GoodMinNotional: conf.RektCloseOutGoodMinNotional,
+			}))
+		}
+		if conf.VegaNetworkParametersEnabled == true {
+			registry.Register(eventhandlers.NewNetworkParameterHandler(referenceEthereumConfig, store.SetEthereumConfig))
+
+			watcher := eventhandlers.NewParameterWatchHandler(dataClient, store, eventhandlers.ParameterWatchSettings{
+				Allow: conf.NetworkParametersAllow,
+				Deny:  conf.NetworkParametersDeny,
+			})
+			// announce the changes made while the bot was stopped
+			notifications, err := watcher.Sync(context.Background())
+			if err != nil {
+				captureError(err, conf.SentryEnabled)
+			}
+			for _, notification := range notifications {
+				err = socialPost.Send(notification)
 				if err != nil {
-					logError(err, conf.SentryEnabled)
+					captureError(err, conf.SentryEnabled)
 				}
+			}
+			registry.Register(watcher)
+		}
+		if conf.VegaCollateralEnabled == true {
+			registry.Register(eventhandlers.NewCollateralHandler(markets, eventhandlers.CollateralSettings{
+				Thresholds:        conf.CollateralThresholds,
+				DefaultThreshold:  conf.CollateralDefaultThreshold,
+				Window:            conf.CollateralAggregationWindow,
+				EthereumExplorers: conf.EthereumExplorerURLs,
+			}, referenceEthereumConfig))
+		}
+		if conf.VegaOrdersEnabled == true {
+			var botFilter func(string) bool
+			if conf.BotBlacklistEnabled {
+				botFilter = isBot
+			}
+			volumes := tradevolume.NewTracker(conf.WhaleVolumeWindows...)
+			registry.Register(eventhandlers.NewWhaleHandler(books, volumes, markets, eventhandlers.WhaleSettings{
+				Strategy:         conf.WhaleStrategy,
+				MarketStrategies: conf.WhaleMarketStrategies,
+				Threshold:        conf.WhaleThreshold,
+				DepthRange:       conf.WhaleDepthRange,
+				OrdersThreshold:  conf.WhaleOrdersThreshold,
+				VolumeMultiple:   conf.WhaleVolumeMultiple,
+				VolumePercentile: conf.WhaleVolumePercentile,
+				TradeWindow:      conf.WhaleTradeWindow,
+			}, botFilter))
+		}
 
-				for _, event := range resp.Events {
-					switch eventTypeLoop := event.Type; eventTypeLoop {
-					case proto.BusEventType_BUS_EVENT_TYPE_NETWORK_PARAMETER: // Network has been reset (network ID has changed/block height reset)
-						networkParameter := event.GetNetworkParameter()
-						if networkParameter.Key == "blockchains.ethereumConfig" {
-							message := socialevents.NetworkParametesNotification(dataClient, networkParameter, currentEthereumConfig)
-							if message != "" {
-								if conf.Debug {
-									printEvent(event)
-								}
-								socialPost.SendMessage(message)
-
-								// reinitialize network parameters
-								err = writeEthereumConfig(networkParameter)
-								if err != nil {
-									logError(err, conf.SentryEnabled)
-								}
-							}
-						}
-					case proto.BusEventType_BUS_EVENT_TYPE_LOSS_SOCIALIZATION: // Loss socialisation alerts (distribution of funds generated by defaulting traders)
-						if conf.Debug {
-							printEvent(event)
-						}
-						lossSocialization := event.GetLossSocialization()
-						message, err := socialevents.LossSocializationNotification(dataClient, lossSocialization)
-						if err != nil {
-							logError(err, conf.SentryEnabled)
-						}
-						log.Println(message)
-						socialPost.SendMessage(message)
-					case proto.BusEventType_BUS_EVENT_TYPE_AUCTION: // Market price monitoring auction started/ended
-						if conf.Debug {
-							printEvent(event)
-						}
-						auction := event.GetAuction()
-						message, err := socialevents.AuctionNotification(dataClient, auction, conf.VegaAuctionsExtendEnabled)
-						log.Println(message)
-						if err != nil {
-							logError(err, conf.SentryEnabled)
-						}
-						socialPost.SendMessage(message)
-					case proto.BusEventType_BUS_EVENT_TYPE_PROPOSAL: //New Market Proposal created, updated, enacted
-						if conf.Debug {
-							printEvent(event)
-						}
-						proposal := event.GetProposal()
-						message, err := socialevents.MarketProposalNotification(dataClient, proposal.Id, proposal.State)
-						if err != nil {
-							logError(err, conf.SentryEnabled)
-						}
-						log.Println(message)
-						socialPost.SendMessage(message)
-					case proto.BusEventType_BUS_EVENT_TYPE_TRADE: // Rekt alert
-						trade := event.GetTrade()
-						if trade.Type == proto.Trade_TYPE_NETWORK_CLOSE_OUT_BAD {
-							if conf.Debug {
-								printEvent(event)
-							}
-							message, err := socialevents.RektNotification(dataClient, trade)
-							if err != nil {
-								logError(err, conf.SentryEnabled)
-							}
-							log.Println(message)
-							socialPost.SendMessage(message)
-						}
-					case proto.BusEventType_BUS_EVENT_TYPE_ORDER: // Whale alert
-						order := event.GetOrder()
-						if order.Status == proto.Order_STATUS_ACTIVE {
-							value := order.Size * order.Price
-							marketVal, marketFlag, _ := getMarketValue(dataClient, order.MarketId, order.Side, conf.WhaleOrdersThreshold)
-							if float64(value) > (float64(marketVal)*conf.WhaleThreshold) && marketFlag {
-								if conf.Debug {
-									printEvent(event)
-								}
-
-								if conf.BotBlacklistEnabled {
-									if isBot(order.PartyId) {
-										log.Printf("Party id %s is in the blacklist. Ignoring...", order.PartyId)
-										break
-									} else {
-										log.Printf("Party id %s is not in the blacklist. Continuing...", order.PartyId)
-
-									}
-								}
-								message, err := socialevents.WhaleNotification(dataClient, order)
-								if err != nil {
-									logError(err, conf.SentryEnabled)
-								}
-								log.Println(message)
-								socialPost.SendMessage(message)
-
-							}
-						}
-					case proto.BusEventType_BUS_EVENT_TYPE_MARKET_CREATED:
-						log.Println("BusEventType_BUS_EVENT_TYPE_MARKET_CREATED: ", event)
+		consumer := vegaclient.NewEventBusConsumer(conn, registry.EventTypes(), conf.VegaEventsBatchSize, conf.GrpcReconnectMaxBackoff)
+		// order events sent while the stream was down are lost
+		consumer.OnReconnect(books.Reset)
+		lastBlock, err := store.LastBlock()
+		if err != nil {
+			captureError(err, conf.SentryEnabled)
+		}
+		if lastBlock != "" {
+			log.Printf("Last processed block: %s\n", lastBlock)
+		}
 
-					}
+		err = consumer.Run(context.Background(), func(event *proto.BusEvent) {
+			notifications, err := registry.Dispatch(context.Background(), event)
+			if err != nil {
+				logError(err, conf.SentryEnabled)
+			}
+			if event.Block != lastBlock {
+				lastBlock = event.Block
+				err = store.SetLastBlock(lastBlock)
+				if err != nil {
+					captureError(err, conf.SentryEnabled)
 				}
 			}
-		}()
-
-		// When the batchSize is too small -> "rpc error: code = Unknown desc = EOF"
-		log.Printf("Listening to event types: %v\n", eventType)
-		observerEvent := api.ObserveEventBusRequest{Type: eventType, BatchSize: conf.VegaEventsBatchSize}
-		events.Send(&observerEvent)
-		events.CloseSend()
-
-		<-done //we will wait until all response is received
+			if conf.Debug && len(notifications) > 0 {
+				printEvent(event)
+			}
+			for _, notification := range notifications {
+				log.Println(notification.Message)
+				err = socialPost.Send(notification)
+				if err != nil {
+					captureError(err, conf.SentryEnabled)
+				}
+			}
+		})
+		if err != nil {
+			logError(err, conf.SentryEnabled)
+		}
 
 		log.Println("finished")
 	}()
@@ -0,0 +1,144 @@
+package marketcache
+
+import (
+	"log"
+	"sync"
+
+	"github.com/baldator/vega-bot/errorpolicy"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+// Info is the market metadata used by notifications
+type Info struct {
+	ID              string
+	Name            string
+	Code            string
+	DecimalPlaces   uint64
+	SettlementAsset string
+	TradingMode     proto.Market_TradingMode
+}
+
+// Cache keeps the markets of the network and their assets in memory
+type Cache struct {
+	dataClient api.TradingDataServiceClient
+	mu         sync.RWMutex
+	markets    map[string]*proto.Market
+	assets     map[string]*proto.Asset
+}
+
+// NewCache creates an empty market cache
+func NewCache(dataClient api.TradingDataServiceClient) *Cache {
+	return &Cache{
+		dataClient: dataClient,
+		markets:    make(map[string]*proto.Market),
+		assets:     make(map[string]*proto.Asset),
+	}
+}
+
+// Warm loads all the markets of the network
+func (cache *Cache) Warm(ctx context.Context) error {
+	response, err := cache.dataClient.Markets(ctx, &api.MarketsRequest{})
+	if err != nil {
+		return errors.Wrap(err, "could not get markets")
+	}
+
+	for _, market := range response.Markets {
+		cache.Update(market)
+	}
+	log.Printf("Market cache warmed with %d markets\n", len(response.Markets))
+
+	return nil
+}
+
+// Update stores a new version of a market
+func (cache *Cache) Update(market *proto.Market) {
+	if market == nil || market.Id == "" {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.markets[market.Id] = market
+}
+
+// Markets returns all the cached markets
+func (cache *Cache) Markets() []*proto.Market {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	markets := make([]*proto.Market, 0, len(cache.markets))
+	for _, market := range cache.markets {
+		markets = append(markets, market)
+	}
+	return markets
+}
+
+// Market returns a market, fetching it from the node when it is not cached
+func (cache *Cache) Market(ctx context.Context, marketID string) (*proto.Market, error) {
+	cache.mu.RLock()
+	market, ok := cache.markets[marketID]
+	cache.mu.RUnlock()
+	if ok {
+		return market, nil
+	}
+
+	response, err := cache.dataClient.MarketByID(ctx, &api.MarketByIDRequest{MarketId: marketID})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get market %s", marketID)
+	}
+
+	market = response.GetMarket()
+	if market.GetTradableInstrument().GetInstrument() == nil {
+		return nil, errorpolicy.NewPermanent(errors.New("market " + marketID + " not found"))
+	}
+	cache.Update(market)
+
+	return market, nil
+}
+
+// Info returns the metadata of a market
+func (cache *Cache) Info(ctx context.Context, marketID string) (Info, error) {
+	market, err := cache.Market(ctx, marketID)
+	if err != nil {
+		return Info{}, err
+	}
+
+	instrument := market.TradableInstrument.Instrument
+	return Info{
+		ID:              market.Id,
+		Name:            instrument.Name,
+		Code:            instrument.Code,
+		DecimalPlaces:   market.DecimalPlaces,
+		SettlementAsset: instrument.GetFuture().GetSettlementAsset(),
+		TradingMode:     market.TradingMode,
+	}, nil
+}
+
+// Asset returns an asset, fetching it from the node when it is not cached
+func (cache *Cache) Asset(ctx context.Context, assetID string) (*proto.Asset, error) {
+	cache.mu.RLock()
+	asset, ok := cache.assets[assetID]
+	cache.mu.RUnlock()
+	if ok {
+		return asset, nil
+	}
+
+	response, err := cache.dataClient.AssetByID(ctx, &api.AssetByIDRequest{Id: assetID})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get asset %s", assetID)
+	}
+
+	asset = response.GetAsset()
+	if asset == nil {
+		return nil, errorpolicy.NewPermanent(errors.New("asset " + assetID + " not found"))
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.assets[assetID] = asset
+	return asset, nil
+}
@@ -0,0 +1,215 @@
+package orderbook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+// snapshotMaxAge is the time after which the book of a market is seeded
+// again, to pick up the changes of the orders placed before the snapshot
+const snapshotMaxAge = 10 * time.Minute
+
+type entry struct {
+	side      proto.Side
+	price     uint64
+	remaining uint64
+}
+
+// Book is the order book of a market, seeded from the market depth and updated
+// with the events of the orders placed since
+type Book struct {
+	orders map[string]entry
+	levels map[proto.Side]map[uint64]uint64
+	seeded time.Time
+}
+
+func newBook(seeded time.Time) *Book {
+	return &Book{
+		seeded: seeded,
+		orders: make(map[string]entry),
+		levels: map[proto.Side]map[uint64]uint64{
+			proto.Side_SIDE_BUY:  make(map[uint64]uint64),
+			proto.Side_SIDE_SELL: make(map[uint64]uint64),
+		},
+	}
+}
+
+// Apply updates the book with the new state of an order. Orders leave the
+// book when they are no longer active or have nothing left to trade. The
+// orders placed before the book was seeded are already part of its depth,
+// their changes are picked up by the next snapshot
+func (book *Book) Apply(order *proto.Order) {
+	if _, known := book.orders[order.Id]; !known && time.Unix(0, order.CreatedAt).Before(book.seeded) {
+		return
+	}
+	book.remove(order.Id)
+	if order.Status != proto.Order_STATUS_ACTIVE || order.Remaining == 0 {
+		return
+	}
+	levels, ok := book.levels[order.Side]
+	if !ok {
+		return
+	}
+
+	book.orders[order.Id] = entry{side: order.Side, price: order.Price, remaining: order.Remaining}
+	levels[order.Price] += order.Remaining
+}
+
+func (book *Book) remove(orderID string) {
+	previous, ok := book.orders[orderID]
+	if !ok {
+		return
+	}
+	delete(book.orders, orderID)
+
+	levels := book.levels[previous.side]
+	if levels[previous.price] <= previous.remaining {
+		delete(levels, previous.price)
+		return
+	}
+	levels[previous.price] -= previous.remaining
+}
+
+// Levels returns the number of price levels of a side
+func (book *Book) Levels(side proto.Side) int {
+	return len(book.levels[side])
+}
+
+// Liquidity returns the total value (volume * price) of a side
+func (book *Book) Liquidity(side proto.Side) uint64 {
+	var liquidity uint64
+	for price, volume := range book.levels[side] {
+		liquidity += volume * price
+	}
+	return liquidity
+}
+
+// Mid returns the mid price. ok is false when one of the sides is empty
+func (book *Book) Mid() (mid float64, ok bool) {
+	var bestBid, bestAsk uint64
+	for price := range book.levels[proto.Side_SIDE_BUY] {
+		if price > bestBid {
+			bestBid = price
+		}
+	}
+	for price := range book.levels[proto.Side_SIDE_SELL] {
+		if bestAsk == 0 || price < bestAsk {
+			bestAsk = price
+		}
+	}
+	if bestBid == 0 || bestAsk == 0 {
+		return 0, false
+	}
+
+	return (float64(bestBid) + float64(bestAsk)) / 2, true
+}
+
+// Depth returns the value of a side within percent % of the mid price. The
+// whole side is used when percent is 0 or the mid price is unknown
+func (book *Book) Depth(side proto.Side, percent float64) uint64 {
+	mid, ok := book.Mid()
+	if percent <= 0 || !ok {
+		return book.Liquidity(side)
+	}
+
+	low := mid * (1 - percent/100)
+	high := mid * (1 + percent/100)
+	var depth uint64
+	for price, volume := range book.levels[side] {
+		if float64(price) >= low && float64(price) <= high {
+			depth += volume * price
+		}
+	}
+	return depth
+}
+
+// Share returns the part of the depth within percent % of the mid price
+// represented by the order
+func (book *Book) Share(order *proto.Order, percent float64) float64 {
+	depth := book.Depth(order.Side, percent)
+	if depth == 0 {
+		return 0
+	}
+	return float64(order.Size*order.Price) / float64(depth)
+}
+
+// Books keeps the order book of every market. The book of a market is seeded
+// from its market depth the first time it is used, and again when the
+// snapshot gets old
+type Books struct {
+	dataClient api.TradingDataServiceClient
+	mu         sync.Mutex
+	books      map[string]*Book
+}
+
+// NewBooks creates an empty set of order books
+func NewBooks(dataClient api.TradingDataServiceClient) *Books {
+	return &Books{
+		dataClient: dataClient,
+		books:      make(map[string]*Book),
+	}
+}
+
+// Apply updates the book of the order market and calls fn with it. The book
+// must not be used after fn returns
+func (books *Books) Apply(ctx context.Context, order *proto.Order, fn func(book *Book) error) error {
+	books.mu.Lock()
+	defer books.mu.Unlock()
+
+	book, ok := books.books[order.MarketId]
+	if !ok || time.Since(book.seeded) > snapshotMaxAge {
+		var err error
+		book, err = books.snapshot(ctx, order.MarketId)
+		if err != nil {
+			return err
+		}
+		books.books[order.MarketId] = book
+	}
+
+	book.Apply(order)
+	return fn(book)
+}
+
+// View calls fn with the book of a market if it has already been seeded. The
+// book must not be used after fn returns
+func (books *Books) View(marketID string, fn func(book *Book)) bool {
+	books.mu.Lock()
+	defer books.mu.Unlock()
+
+	book, ok := books.books[marketID]
+	if !ok {
+		return false
+	}
+	fn(book)
+	return true
+}
+
+// Reset drops all the books. They are seeded again on their next use, which
+// is needed when order events may have been missed
+func (books *Books) Reset() {
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	books.books = make(map[string]*Book)
+}
+
+func (books *Books) snapshot(ctx context.Context, marketID string) (*Book, error) {
+	seeded := time.Now()
+	response, err := books.dataClient.MarketDepth(ctx, &api.MarketDepthRequest{MarketId: marketID})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get market depth for market %s", marketID)
+	}
+
+	book := newBook(seeded)
+	for _, level := range response.Buy {
+		book.levels[proto.Side_SIDE_BUY][level.Price] += level.Volume
+	}
+	for _, level := range response.Sell {
+		book.levels[proto.Side_SIDE_SELL][level.Price] += level.Volume
+	}
+	return book, nil
+}
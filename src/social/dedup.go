@@ -0,0 +1,60 @@
+package social
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/baldator/vega-bot/state"
+)
+
+// pruneInterval is how often the expired sent messages are forgotten
+const pruneInterval = time.Hour
+
+// Deduplicator remembers the messages sent to every platform for ttl so that
+// events replayed after a reconnection or a restart are not published twice
+type Deduplicator struct {
+	store     state.StateStore
+	ttl       time.Duration
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewDeduplicator creates a deduplicator keeping sent messages in store
+func NewDeduplicator(store state.StateStore, ttl time.Duration) *Deduplicator {
+	return &Deduplicator{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Sent tells if a message with the given key has been sent to a platform
+// within the ttl
+func (dedup *Deduplicator) Sent(key string, platform string) bool {
+	at, ok, err := dedup.store.Sent(platform + "/" + key)
+	if err != nil {
+		log.Printf("Could not check sent messages: %s\n", err)
+		return false
+	}
+	return ok && time.Since(at) < dedup.ttl
+}
+
+// MarkSent records that a message with the given key has been sent to a platform
+func (dedup *Deduplicator) MarkSent(key string, platform string) {
+	now := time.Now()
+	err := dedup.store.SetSent(platform+"/"+key, now)
+	if err != nil {
+		log.Printf("Could not record sent message: %s\n", err)
+	}
+
+	dedup.mu.Lock()
+	defer dedup.mu.Unlock()
+	if now.Sub(dedup.lastPrune) < pruneInterval {
+		return
+	}
+	dedup.lastPrune = now
+	err = dedup.store.PruneSent(now.Add(-dedup.ttl))
+	if err != nil {
+		log.Printf("Could not prune sent messages: %s\n", err)
+	}
+}
@@ -0,0 +1,36 @@
+package social
+
+import "github.com/baldator/vega-bot/socialevents"
+
+// DiscordWebhook sends notifications to a Discord channel webhook
+type DiscordWebhook struct {
+	webhookURL string
+}
+
+type discordPayload struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// NewDiscordWebhook creates a Discord webhook transport
+func NewDiscordWebhook(webhookURL string) *DiscordWebhook {
+	return &DiscordWebhook{webhookURL: webhookURL}
+}
+
+// Name returns the transport name
+func (transport *DiscordWebhook) Name() string {
+	return "discord-webhook"
+}
+
+// Platform returns the social media the transport publishes on
+func (transport *DiscordWebhook) Platform() string {
+	return "discord"
+}
+
+// Send publishes a notification as a Discord embed
+func (transport *DiscordWebhook) Send(notification socialevents.Notification) error {
+	payload := discordPayload{Embeds: renderDiscordEmbeds(notification)}
+
+	_, err := postJSON(transport.webhookURL, payload, nil)
+	return err
+}
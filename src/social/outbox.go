@@ -0,0 +1,410 @@
+package social
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+const (
+	outboxFile     = "outbox.json"
+	deadLetterFile = "deadletter.jsonl"
+	maxRetryDelay  = 10 * time.Minute
+)
+
+// outboxEntry is the delivery state of a notification on one transport
+type outboxEntry struct {
+	ID           string                    `json:"id"`
+	Transport    string                    `json:"transport"`
+	Notification socialevents.Notification `json:"notification"`
+	Attempts     int                       `json:"attempts"`
+	NextAttempt  time.Time                 `json:"next_attempt"`
+	LastError    string                    `json:"last_error,omitempty"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	Count        int                       `json:"count,omitempty"`
+	Keys         []string                  `json:"keys,omitempty"`
+}
+
+// count returns the number of notifications the entry stands for
+func (entry *outboxEntry) count() int {
+	if entry.Count > 1 {
+		return entry.Count
+	}
+	return 1
+}
+
+// Outbox is a persistent queue of notifications waiting to be delivered.
+// Failed deliveries are retried with exponential backoff and moved to the
+// dead letter file once they run out of attempts. When a transport is over
+// its rate limit, its waiting notifications of the same type and market are
+// coalesced into a single summary, except the critical ones
+type Outbox struct {
+	mu             sync.Mutex
+	path           string
+	deadLetterPath string
+	entries        []*outboxEntry
+	transports     map[string]Transport
+	limiters       map[string]*tokenBucket
+	dedup          *Deduplicator
+	maxAttempts    int
+	retryDelay     time.Duration
+	sequence       int
+	wake           chan struct{}
+}
+
+// NewOutbox creates an outbox stored in dataDir and loads the entries left by a previous run
+func NewOutbox(dataDir string, transports []Transport, maxAttempts int, retryDelay time.Duration) (*Outbox, error) {
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		log.Println("Creating directory")
+		os.Mkdir(dataDir, os.ModePerm)
+	}
+
+	outbox := &Outbox{
+		path:           filepath.Join(dataDir, outboxFile),
+		deadLetterPath: filepath.Join(dataDir, deadLetterFile),
+		transports:     make(map[string]Transport),
+		limiters:       make(map[string]*tokenBucket),
+		maxAttempts:    maxAttempts,
+		retryDelay:     retryDelay,
+		wake:           make(chan struct{}, 1),
+	}
+	for _, transport := range transports {
+		outbox.transports[transport.Name()] = transport
+	}
+
+	err := outbox.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return outbox, nil
+}
+
+// SetRateLimit limits the messages sent by the transports of a platform to
+// perMinute messages per minute with bursts of up to burst messages
+func (outbox *Outbox) SetRateLimit(platform string, perMinute float64, burst int) {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	for name, transport := range outbox.transports {
+		if transport.Platform() == platform {
+			outbox.limiters[name] = newTokenBucket(perMinute, burst)
+		}
+	}
+}
+
+// SetDeduplicator makes the outbox send every notification at most once per
+// platform. Notifications already sent are dropped
+func (outbox *Outbox) SetDeduplicator(dedup *Deduplicator) {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	outbox.dedup = dedup
+}
+
+// Enqueue adds a notification for every transport and persists it
+func (outbox *Outbox) Enqueue(notification socialevents.Notification, transports []Transport) error {
+	outbox.mu.Lock()
+	now := time.Now()
+	key := notification.Key()
+	for _, transport := range transports {
+		if outbox.dedup != nil && outbox.dedup.Sent(key, transport.Platform()) {
+			log.Printf("Message already sent to %s, ignoring it: %s\n", transport.Platform(), notification.Message)
+			continue
+		}
+		outbox.sequence++
+		outbox.entries = append(outbox.entries, &outboxEntry{
+			ID:           strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.Itoa(outbox.sequence),
+			Transport:    transport.Name(),
+			Notification: notification,
+			NextAttempt:  now,
+			CreatedAt:    now,
+			Keys:         []string{key},
+		})
+	}
+	err := outbox.save()
+	outbox.mu.Unlock()
+
+	outbox.notify()
+	return err
+}
+
+// Run delivers the queued notifications
+func (outbox *Outbox) Run() {
+	for {
+		wait := outbox.deliverDue()
+
+		select {
+		case <-outbox.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (outbox *Outbox) notify() {
+	select {
+	case outbox.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverDue sends the entries whose next attempt is due and returns how long
+// to wait before the next one
+func (outbox *Outbox) deliverDue() time.Duration {
+	outbox.mu.Lock()
+	now := time.Now()
+	outbox.coalesceLimited(now)
+
+	var due []*outboxEntry
+	for _, entry := range outbox.entries {
+		if entry.NextAttempt.After(now) {
+			continue
+		}
+		limiter, ok := outbox.limiters[entry.Transport]
+		if ok && !limiter.take(now) {
+			continue
+		}
+		due = append(due, entry)
+	}
+	outbox.mu.Unlock()
+
+	for _, entry := range due {
+		outbox.deliver(entry)
+	}
+
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	wait := maxRetryDelay
+	now = time.Now()
+	for _, entry := range outbox.entries {
+		entryWait := entry.NextAttempt.Sub(now)
+		if limiter, ok := outbox.limiters[entry.Transport]; ok {
+			limiterWait := limiter.wait(now)
+			if limiterWait > entryWait {
+				entryWait = limiterWait
+			}
+		}
+		if entryWait < wait {
+			wait = entryWait
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+func (outbox *Outbox) deliver(entry *outboxEntry) {
+	outbox.mu.Lock()
+	dedup := outbox.dedup
+	outbox.mu.Unlock()
+
+	transport, ok := outbox.transports[entry.Transport]
+	duplicate := ok && sent(dedup, entry, transport.Platform())
+	var err error
+	if ok && !duplicate {
+		err = transport.Send(entry.Notification)
+		if err == nil && dedup != nil {
+			for _, key := range entry.Keys {
+				dedup.MarkSent(key, transport.Platform())
+			}
+		}
+	}
+
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	switch {
+	case duplicate:
+		log.Printf("Message %s already sent to %s, dropping it\n", entry.ID, transport.Platform())
+		outbox.remove(entry)
+	case !ok:
+		log.Printf("Transport %s is not configured anymore, moving message %s to the dead letter file\n", entry.Transport, entry.ID)
+		entry.LastError = "transport not configured"
+		outbox.deadLetter(entry)
+	case err == nil:
+		log.Printf("Message sent to %s: %s\n", entry.Transport, entry.Notification.Message)
+		outbox.remove(entry)
+	default:
+		entry.Attempts++
+		entry.LastError = err.Error()
+		if entry.Attempts >= outbox.maxAttempts {
+			log.Printf("Could not send message %s to %s after %d attempts, moving it to the dead letter file: %s\n", entry.ID, entry.Transport, entry.Attempts, err)
+			outbox.deadLetter(entry)
+			break
+		}
+		delay := outbox.backoff(entry.Attempts)
+		entry.NextAttempt = time.Now().Add(delay)
+		log.Printf("Could not send message %s to %s, retrying in %s: %s\n", entry.ID, entry.Transport, delay, err)
+	}
+
+	err = outbox.save()
+	if err != nil {
+		log.Printf("Could not save outbox: %s\n", err)
+	}
+}
+
+// sent tells if all the notifications of an entry have already been sent to a platform
+func sent(dedup *Deduplicator, entry *outboxEntry, platform string) bool {
+	if dedup == nil || len(entry.Keys) == 0 {
+		return false
+	}
+
+	for _, key := range entry.Keys {
+		if !dedup.Sent(key, platform) {
+			return false
+		}
+	}
+	return true
+}
+
+// backoff returns the exponential delay before the next attempt with up to 50% jitter
+func (outbox *Outbox) backoff(attempts int) time.Duration {
+	delay := outbox.retryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay = delay * 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay/2 + delay/4 + jitter
+}
+
+// coalesceLimited merges the due entries of rate limited transports when there
+// are more of them than the transport can send now. Critical notifications are
+// never merged, their details matter. It must be called with the lock held
+func (outbox *Outbox) coalesceLimited(now time.Time) {
+	type groupKey struct {
+		transport        string
+		notificationType string
+		marketID         string
+	}
+
+	dueCount := make(map[string]int)
+	for _, entry := range outbox.entries {
+		if !entry.NextAttempt.After(now) {
+			dueCount[entry.Transport]++
+		}
+	}
+
+	var order []groupKey
+	groups := make(map[groupKey][]*outboxEntry)
+	for _, entry := range outbox.entries {
+		limiter, ok := outbox.limiters[entry.Transport]
+		if !ok || entry.NextAttempt.After(now) || limiter.available(now) >= dueCount[entry.Transport] {
+			continue
+		}
+		if entry.Notification.Severity >= socialevents.SeverityCritical {
+			continue
+		}
+		key := groupKey{entry.Transport, entry.Notification.Type, entry.Notification.MarketID}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+
+	changed := false
+	for _, key := range order {
+		entries := groups[key]
+		if len(entries) < 2 {
+			continue
+		}
+
+		summary := coalesce(entries)
+		for i, entry := range outbox.entries {
+			if entry == entries[0] {
+				outbox.entries[i] = summary
+				break
+			}
+		}
+		for _, entry := range entries[1:] {
+			outbox.remove(entry)
+		}
+		log.Printf("Coalesced %d messages for %s: %s\n", summary.Count, key.transport, summary.Notification.Message)
+		changed = true
+	}
+
+	if changed {
+		err := outbox.save()
+		if err != nil {
+			log.Printf("Could not save outbox: %s\n", err)
+		}
+	}
+}
+
+func (outbox *Outbox) remove(entry *outboxEntry) {
+	for i, e := range outbox.entries {
+		if e == entry {
+			outbox.entries = append(outbox.entries[:i], outbox.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (outbox *Outbox) deadLetter(entry *outboxEntry) {
+	outbox.remove(entry)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Could not encode dead letter %s: %s\n", entry.ID, err)
+		return
+	}
+
+	file, err := os.OpenFile(outbox.deadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Could not open dead letter file: %s\n", err)
+		return
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		log.Printf("Could not write dead letter %s: %s\n", entry.ID, err)
+	}
+}
+
+func (outbox *Outbox) load() error {
+	content, err := ioutil.ReadFile(outbox.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(content, &outbox.entries)
+	if err != nil {
+		return err
+	}
+	if len(outbox.entries) > 0 {
+		log.Printf("Redelivering %d messages from the outbox\n", len(outbox.entries))
+	}
+
+	return nil
+}
+
+// save writes the outbox atomically. It must be called with the lock held
+func (outbox *Outbox) save() error {
+	content, err := json.MarshalIndent(outbox.entries, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := outbox.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, outbox.path)
+}
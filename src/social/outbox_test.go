@@ -0,0 +1,167 @@
+package social
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+// recordingTransport keeps the notifications it is asked to send
+type recordingTransport struct {
+	sent []socialevents.Notification
+}
+
+func (transport *recordingTransport) Name() string {
+	return "recording"
+}
+
+func (transport *recordingTransport) Platform() string {
+	return "test"
+}
+
+func (transport *recordingTransport) Send(notification socialevents.Notification) error {
+	transport.sent = append(transport.sent, notification)
+	return nil
+}
+
+func newTestOutbox(t *testing.T, burst int) (*Outbox, *recordingTransport) {
+	transport := &recordingTransport{}
+	outbox, err := NewOutbox(t.TempDir(), []Transport{transport}, 3, time.Second)
+	if err != nil {
+		t.Fatalf("Could not create outbox: %v", err)
+	}
+	if burst > 0 {
+		outbox.SetRateLimit("test", 1, burst)
+	}
+	return outbox, transport
+}
+
+func enqueue(t *testing.T, outbox *Outbox, transport Transport, notifications ...socialevents.Notification) {
+	for _, notification := range notifications {
+		err := outbox.Enqueue(notification, []Transport{transport})
+		if err != nil {
+			t.Fatalf("Could not enqueue: %v", err)
+		}
+	}
+}
+
+func whaleTrade(marketID string, message string) socialevents.Notification {
+	return socialevents.Notification{
+		Type:     socialevents.WhaleTradeNotificationType,
+		MarketID: marketID,
+		Message:  message,
+		Value:    10,
+	}
+}
+
+func bridgeAddress(message string) socialevents.Notification {
+	return socialevents.Notification{
+		Type:     socialevents.BridgeAddressNotificationType,
+		Message:  message,
+		Severity: socialevents.SeverityCritical,
+	}
+}
+
+func TestCoalesceLimited(t *testing.T) {
+	tests := []struct {
+		name          string
+		burst         int
+		notifications []socialevents.Notification
+		wantMessages  []string
+		wantCounts    []int
+	}{
+		{
+			name:  "same type and market are merged",
+			burst: 1,
+			notifications: []socialevents.Notification{
+				whaleTrade("m1", "first"),
+				whaleTrade("m2", "other market"),
+				whaleTrade("m1", "second"),
+				whaleTrade("m1", "third"),
+			},
+			wantMessages: []string{"", "other market"},
+			wantCounts:   []int{3, 0},
+		},
+		{
+			name:  "critical notifications are never merged",
+			burst: 1,
+			notifications: []socialevents.Notification{
+				bridgeAddress("bridge moved to 0x1"),
+				bridgeAddress("bridge moved to 0x2"),
+				whaleTrade("m1", "first"),
+				whaleTrade("m1", "second"),
+			},
+			wantMessages: []string{"bridge moved to 0x1", "bridge moved to 0x2", ""},
+			wantCounts:   []int{0, 0, 2},
+		},
+		{
+			name:  "nothing is merged within the limit",
+			burst: 5,
+			notifications: []socialevents.Notification{
+				whaleTrade("m1", "first"),
+				whaleTrade("m1", "second"),
+			},
+			wantMessages: []string{"first", "second"},
+			wantCounts:   []int{0, 0},
+		},
+		{
+			name:  "nothing is merged without limit",
+			burst: 0,
+			notifications: []socialevents.Notification{
+				whaleTrade("m1", "first"),
+				whaleTrade("m1", "second"),
+			},
+			wantMessages: []string{"first", "second"},
+			wantCounts:   []int{0, 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outbox, transport := newTestOutbox(t, test.burst)
+			enqueue(t, outbox, transport, test.notifications...)
+
+			outbox.mu.Lock()
+			outbox.coalesceLimited(time.Now())
+			entries := outbox.entries
+			outbox.mu.Unlock()
+
+			if len(entries) != len(test.wantMessages) {
+				t.Fatalf("%d entries, want %d", len(entries), len(test.wantMessages))
+			}
+			for i, entry := range entries {
+				// an empty message stands for a summary
+				if test.wantMessages[i] != "" && entry.Notification.Message != test.wantMessages[i] {
+					t.Errorf("entry %d: message %q, want %q", i, entry.Notification.Message, test.wantMessages[i])
+				}
+				if entry.Count != test.wantCounts[i] {
+					t.Errorf("entry %d: count %d, want %d", i, entry.Count, test.wantCounts[i])
+				}
+				if entry.Count > 0 && len(entry.Keys) != entry.Count {
+					t.Errorf("entry %d: %d keys, want one per merged notification", i, len(entry.Keys))
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverDueRespectsRateLimit(t *testing.T) {
+	outbox, transport := newTestOutbox(t, 2)
+	enqueue(t, outbox, transport,
+		bridgeAddress("bridge moved to 0x1"),
+		bridgeAddress("bridge moved to 0x2"),
+		bridgeAddress("bridge moved to 0x3"),
+	)
+
+	wait := outbox.deliverDue()
+	if len(transport.sent) != 2 {
+		t.Fatalf("%d messages sent, want the burst of 2", len(transport.sent))
+	}
+	// one message per minute
+	if wait <= 50*time.Second || wait > time.Minute {
+		t.Errorf("wait = %s, want about a minute", wait)
+	}
+	if len(outbox.entries) != 1 || outbox.entries[0].Notification.Message != "bridge moved to 0x3" {
+		t.Errorf("the third critical message must wait in the outbox, got %d entries", len(outbox.entries))
+	}
+}
@@ -0,0 +1,88 @@
+package social
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+// Post is the payload accepted by the post-to-socials send endpoint. ReplyTo
+// is the ID of the post to answer on the platform
+type Post struct {
+	Message string   `json:"message"`
+	Images  []string `json:"images,omitempty"`
+	ReplyTo string   `json:"replyTo,omitempty"`
+}
+
+// PostToSocials sends posts to one platform through the post-to-socials service
+type PostToSocials struct {
+	serviceURL    string
+	serviceKey    string
+	serviceSecret string
+	platform      string
+}
+
+// NewPostToSocials creates a post-to-socials transport for a platform (twitter, discord, slack or telegram)
+func NewPostToSocials(serviceURL string, serviceKey string, serviceSecret string, platform string) *PostToSocials {
+	return &PostToSocials{
+		serviceURL:    serviceURL,
+		serviceKey:    serviceKey,
+		serviceSecret: serviceSecret,
+		platform:      platform,
+	}
+}
+
+// CheckPostToSocialsStatus verifies the post-to-socials service is up
+func CheckPostToSocialsStatus(serviceURL string) error {
+	url := serviceURL + "/status"
+	resp, err := http.Get(url)
+	if err != nil {
+		return errors.New("Could not reach social webservice. " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return errors.New("Social webservice return code is not 200. Webservice url: " + url)
+	}
+
+	return nil
+}
+
+// Name returns the transport name
+func (transport *PostToSocials) Name() string {
+	return "post-to-socials/" + transport.platform
+}
+
+// Platform returns the social media the transport publishes on
+func (transport *PostToSocials) Platform() string {
+	return transport.platform
+}
+
+// Send publishes a notification through the post-to-socials service. Twitter
+// gets a rendering trimmed to the tweet length
+func (transport *PostToSocials) Send(notification socialevents.Notification) error {
+	post := Post{
+		Message: renderText(notification),
+		Images:  notification.Images,
+		ReplyTo: notification.ReplyTo[transport.platform],
+	}
+	if transport.platform == "twitter" {
+		post.Message = renderTwitter(notification)
+	}
+
+	url := transport.serviceURL + "/send/" + transport.platform
+	headers := map[string]string{
+		"key":    transport.serviceKey,
+		"secret": transport.serviceSecret,
+	}
+
+	body, err := postJSON(url, post, headers)
+	if err != nil {
+		return err
+	}
+	log.Println(string(body))
+
+	return nil
+}
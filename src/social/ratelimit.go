@@ -0,0 +1,158 @@
+package social
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+// tokenBucket limits the number of messages sent to a platform
+type tokenBucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perMinute float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   perMinute / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (bucket *tokenBucket) refill(now time.Time) {
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+}
+
+// available returns the number of messages that can be sent now
+func (bucket *tokenBucket) available(now time.Time) int {
+	bucket.refill(now)
+	return int(bucket.tokens)
+}
+
+// take consumes a token if one is available
+func (bucket *tokenBucket) take(now time.Time) bool {
+	bucket.refill(now)
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// wait returns how long until the next token is available
+func (bucket *tokenBucket) wait(now time.Time) time.Duration {
+	bucket.refill(now)
+	if bucket.tokens >= 1 || bucket.rate <= 0 {
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) / bucket.rate * float64(time.Second))
+}
+
+// Plural labels used in coalesced summaries
+var coalescedLabels = map[string]string{
+	socialevents.WhaleNotificationType:                  "whale orders",
+	socialevents.WhaleTradeNotificationType:             "whale trades",
+	socialevents.RektNotificationType:                   "liquidations",
+	socialevents.RektCascadeNotificationType:            "liquidation cascades",
+	socialevents.AuctionNotificationType:                "auction updates",
+	socialevents.ProposalNotificationType:               "proposal updates",
+	socialevents.ProposalProgressNotificationType:       "proposal vote updates",
+	socialevents.ProposalReminderNotificationType:       "proposal reminders",
+	socialevents.LossSocializationNotificationType:      "loss socializations",
+	socialevents.MarketCreationNotificationType:         "new markets",
+	socialevents.MarketUpdateNotificationType:           "market updates",
+	socialevents.DepositNotificationType:                "deposits",
+	socialevents.WithdrawalNotificationType:             "withdrawals",
+	socialevents.NetworkParameterChangeNotificationType: "network parameter changes",
+}
+
+// coalesce merges entries of the same type and market into a single summary
+// entry. entries must belong to the same transport. The summary keeps the
+// delivery attempts of the most retried entry
+func coalesce(entries []*outboxEntry) *outboxEntry {
+	first := entries[0]
+	summary := &outboxEntry{
+		ID:           first.ID,
+		Transport:    first.Transport,
+		Notification: first.Notification,
+		NextAttempt:  first.NextAttempt,
+		CreatedAt:    first.CreatedAt,
+	}
+
+	count := 0
+	total := 0.0
+	severity := socialevents.SeverityInfo
+	for _, entry := range entries {
+		summary.Keys = append(summary.Keys, entry.Keys...)
+		count += entry.count()
+		total += entry.Notification.Value
+		if entry.CreatedAt.Before(summary.CreatedAt) {
+			summary.CreatedAt = entry.CreatedAt
+		}
+		if entry.Notification.Severity > severity {
+			severity = entry.Notification.Severity
+		}
+		if entry.Attempts > summary.Attempts {
+			summary.Attempts = entry.Attempts
+			summary.LastError = entry.LastError
+		}
+	}
+
+	label, ok := coalescedLabels[first.Notification.Type]
+	if !ok {
+		label = first.Notification.Type + " alerts"
+	}
+	title := strconv.Itoa(count) + " " + label
+	if first.Notification.Market != "" {
+		title = title + " on " + first.Notification.Market
+	}
+	message := title + " in the last " + formatWindow(time.Since(summary.CreatedAt))
+	if total != 0 {
+		message = message + " totalling " + strconv.FormatFloat(total, 'f', -1, 64)
+	}
+	if first.Notification.Emoji != "" {
+		message = first.Notification.Emoji + " " + message
+	}
+
+	summary.Count = count
+	summary.Notification = socialevents.Notification{
+		Type:     first.Notification.Type,
+		MarketID: first.Notification.MarketID,
+		Market:   first.Notification.Market,
+		Emoji:    first.Notification.Emoji,
+		Title:    title,
+		Message:  message,
+		Value:    total,
+		Severity: severity,
+	}
+	summary.Notification.AddField("Alerts", strconv.Itoa(count))
+	if total != 0 {
+		summary.Notification.AddField("Total", strconv.FormatFloat(total, 'f', -1, 64))
+	}
+
+	return summary
+}
+
+func formatWindow(window time.Duration) string {
+	minutes := int(window.Round(time.Minute) / time.Minute)
+	if minutes <= 1 {
+		return "minute"
+	}
+	if minutes < 60 {
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return window.Round(time.Minute).String()
+}
@@ -0,0 +1,127 @@
+package social
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	type step struct {
+		after    time.Duration
+		take     bool
+		wantTake bool
+		wantWait time.Duration
+	}
+	tests := []struct {
+		name      string
+		perMinute float64
+		burst     int
+		steps     []step
+	}{
+		{"burst is available at once", 60, 3, []step{
+			{0, true, true, 0},
+			{0, true, true, 0},
+			{0, true, true, time.Second},
+			{0, true, false, time.Second},
+		}},
+		{"tokens refill at the rate", 60, 1, []step{
+			{0, true, true, time.Second},
+			{500 * time.Millisecond, true, false, 500 * time.Millisecond},
+			{time.Second, true, true, time.Second},
+		}},
+		{"refill is capped by the burst", 60, 2, []step{
+			{0, true, true, 0},
+			{0, true, true, time.Second},
+			{time.Hour, true, true, 0},
+			{time.Hour, true, true, time.Second},
+			{time.Hour, true, false, time.Second},
+		}},
+		{"burst is at least one message", 60, 0, []step{
+			{0, true, true, time.Second},
+			{0, true, false, time.Second},
+		}},
+		{"no rate never waits", 0, 1, []step{
+			{0, true, true, 0},
+			{time.Hour, true, false, 0},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := newTokenBucket(test.perMinute, test.burst)
+			bucket.last = start
+			for i, step := range test.steps {
+				now := start.Add(step.after)
+				if step.take {
+					if got := bucket.take(now); got != step.wantTake {
+						t.Errorf("step %d: take = %v, want %v", i, got, step.wantTake)
+					}
+				}
+				if got := bucket.wait(now); got != step.wantWait {
+					t.Errorf("step %d: wait = %s, want %s", i, got, step.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	created := time.Now().Add(-5 * time.Minute)
+	entry := func(id string, value float64, count int, attempts int, severity socialevents.Severity) *outboxEntry {
+		return &outboxEntry{
+			ID:        id,
+			Transport: "test",
+			Notification: socialevents.Notification{
+				Type:     socialevents.WhaleTradeNotificationType,
+				MarketID: "market",
+				Market:   "BTC/DAI",
+				Emoji:    "🐋",
+				Message:  "whale trade " + id,
+				Value:    value,
+				Severity: severity,
+			},
+			Attempts:  attempts,
+			LastError: "error " + id,
+			CreatedAt: created.Add(time.Duration(len(id)) * time.Second),
+			Count:     count,
+			Keys:      []string{"key-" + id},
+		}
+	}
+
+	summary := coalesce([]*outboxEntry{
+		entry("a", 100, 0, 0, socialevents.SeverityInfo),
+		entry("bb", 250.5, 3, 2, socialevents.SeverityWarning),
+		entry("ccc", 0, 0, 1, socialevents.SeverityInfo),
+	})
+
+	if summary.ID != "a" || summary.Transport != "test" {
+		t.Errorf("summary is %s on %s, want a on test", summary.ID, summary.Transport)
+	}
+	if summary.Count != 5 {
+		t.Errorf("Count = %d, want 5", summary.Count)
+	}
+	if strings.Join(summary.Keys, ",") != "key-a,key-bb,key-ccc" {
+		t.Errorf("Keys = %v, want the keys of every entry", summary.Keys)
+	}
+	if summary.Notification.Value != 350.5 {
+		t.Errorf("Value = %v, want 350.5", summary.Notification.Value)
+	}
+	if summary.Attempts != 2 || summary.LastError != "error bb" {
+		t.Errorf("Attempts = %d (%s), want 2 (error bb)", summary.Attempts, summary.LastError)
+	}
+	if summary.Notification.Severity != socialevents.SeverityWarning {
+		t.Errorf("Severity = %v, want the highest one", summary.Notification.Severity)
+	}
+	if !summary.CreatedAt.Equal(created.Add(time.Second)) {
+		t.Errorf("CreatedAt = %s, want the oldest entry", summary.CreatedAt)
+	}
+	if summary.Notification.Title != "5 whale trades on BTC/DAI" {
+		t.Errorf("Title = %q", summary.Notification.Title)
+	}
+	if want := "🐋 5 whale trades on BTC/DAI in the last 5 minutes totalling 350.5"; summary.Notification.Message != want {
+		t.Errorf("Message = %q, want %q", summary.Notification.Message, want)
+	}
+}
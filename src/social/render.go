@@ -0,0 +1,206 @@
+package social
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+const twitterMaxLength = 280
+
+// Discord embed colours by severity
+var discordColors = map[socialevents.Severity]int{
+	socialevents.SeverityInfo:     0x3498db,
+	socialevents.SeverityWarning:  0xf1c40f,
+	socialevents.SeverityCritical: 0xe74c3c,
+}
+
+// renderText returns the plain text rendering of a notification
+func renderText(notification socialevents.Notification) string {
+	text := notification.Message
+	for _, link := range notification.Links {
+		text = text + "\n" + link.Title + ": " + link.URL
+	}
+	return text
+}
+
+// renderTwitter returns the plain text rendering of a notification trimmed
+// to fit in a tweet. The first link is kept when there is room for it
+func renderTwitter(notification socialevents.Notification) string {
+	text := strings.TrimSpace(notification.Message)
+	if len(notification.Links) > 0 {
+		withLink := text + "\n" + notification.Links[0].URL
+		if utf8.RuneCountInString(withLink) <= twitterMaxLength {
+			return withLink
+		}
+	}
+
+	return truncate(text, twitterMaxLength)
+}
+
+func truncate(text string, maxLength int) string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxLength-1])) + "…"
+}
+
+func title(notification socialevents.Notification) string {
+	if notification.Title == "" {
+		return notification.Message
+	}
+	if notification.Emoji == "" {
+		return notification.Title
+	}
+	return notification.Emoji + " " + notification.Title
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Image       *discordEmbedImage  `json:"image,omitempty"`
+}
+
+// renderDiscordEmbeds returns the Discord embeds of a notification. The first
+// embed holds the notification, the following ones its extra images
+func renderDiscordEmbeds(notification socialevents.Notification) []discordEmbed {
+	embed := discordEmbed{
+		Title: truncate(title(notification), 256),
+		Color: discordColors[notification.Severity],
+	}
+	if len(notification.Fields) == 0 {
+		embed.Description = notification.Message
+	}
+	for _, field := range notification.Fields {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: field.Name, Value: field.Value, Inline: true})
+	}
+	for i, link := range notification.Links {
+		if i == 0 {
+			embed.URL = link.URL
+		}
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: link.Title, Value: link.URL})
+	}
+
+	embeds := []discordEmbed{embed}
+	for i, image := range notification.Images {
+		if i == 0 {
+			embeds[0].Image = &discordEmbedImage{URL: image}
+			continue
+		}
+		embeds = append(embeds, discordEmbed{Color: embed.Color, Image: &discordEmbedImage{URL: image}})
+	}
+
+	return embeds
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackImage struct {
+	Type     string `json:"type"`
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+	ImageURL string      `json:"image_url,omitempty"`
+	AltText  string      `json:"alt_text,omitempty"`
+}
+
+// renderSlackBlocks returns the Slack Block Kit rendering of a notification
+func renderSlackBlocks(notification socialevents.Notification) []slackBlock {
+	blocks := []slackBlock{{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: truncate(title(notification), 150)},
+	}}
+
+	if len(notification.Fields) == 0 {
+		blocks = append(blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: escapeSlack(notification.Message)},
+		})
+	}
+
+	// Slack accepts at most 10 fields per section
+	for start := 0; start < len(notification.Fields); start += 10 {
+		end := start + 10
+		if end > len(notification.Fields) {
+			end = len(notification.Fields)
+		}
+		section := slackBlock{Type: "section"}
+		for _, field := range notification.Fields[start:end] {
+			section.Fields = append(section.Fields, slackText{Type: "mrkdwn", Text: "*" + escapeSlack(field.Name) + "*\n" + escapeSlack(field.Value)})
+		}
+		blocks = append(blocks, section)
+	}
+
+	if len(notification.Links) > 0 {
+		context := slackBlock{Type: "context"}
+		for _, link := range notification.Links {
+			context.Elements = append(context.Elements, slackText{Type: "mrkdwn", Text: "<" + link.URL + "|" + escapeSlack(link.Title) + ">"})
+		}
+		blocks = append(blocks, context)
+	}
+
+	for _, image := range notification.Images {
+		blocks = append(blocks, slackBlock{Type: "image", ImageURL: image, AltText: notification.Title})
+	}
+
+	return blocks
+}
+
+func escapeSlack(text string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return replacer.Replace(text)
+}
+
+// renderTelegramMarkdown returns the Telegram MarkdownV2 rendering of a notification
+func renderTelegramMarkdown(notification socialevents.Notification) string {
+	lines := []string{"*" + escapeTelegram(title(notification)) + "*"}
+	if len(notification.Fields) == 0 {
+		lines = append(lines, escapeTelegram(notification.Message))
+	}
+	for _, field := range notification.Fields {
+		lines = append(lines, "*"+escapeTelegram(field.Name)+":* "+escapeTelegram(field.Value))
+	}
+	for _, link := range notification.Links {
+		url := strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(link.URL)
+		lines = append(lines, "["+escapeTelegram(link.Title)+"]("+url+")")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// escapeTelegram escapes the characters reserved by Telegram MarkdownV2
+func escapeTelegram(text string) string {
+	var builder strings.Builder
+	for _, char := range text {
+		if strings.ContainsRune("\\_*[]()~`>#+-=|{}.!", char) {
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
@@ -0,0 +1,39 @@
+package social
+
+import "github.com/baldator/vega-bot/socialevents"
+
+// SlackWebhook sends notifications to a Slack incoming webhook
+type SlackWebhook struct {
+	webhookURL string
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// NewSlackWebhook creates a Slack incoming webhook transport
+func NewSlackWebhook(webhookURL string) *SlackWebhook {
+	return &SlackWebhook{webhookURL: webhookURL}
+}
+
+// Name returns the transport name
+func (transport *SlackWebhook) Name() string {
+	return "slack-webhook"
+}
+
+// Platform returns the social media the transport publishes on
+func (transport *SlackWebhook) Platform() string {
+	return "slack"
+}
+
+// Send publishes a notification as Slack blocks
+func (transport *SlackWebhook) Send(notification socialevents.Notification) error {
+	payload := slackPayload{
+		Text:   escapeSlack(notification.Message),
+		Blocks: renderSlackBlocks(notification),
+	}
+
+	_, err := postJSON(transport.webhookURL, payload, nil)
+	return err
+}
@@ -1,107 +1,49 @@
 package social
 
 import (
-	"bytes"
 	"errors"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"strconv"
+	"time"
+
+	"github.com/baldator/vega-bot/socialevents"
 )
 
+// Social publishes notifications on all the configured transports through a
+// persistent outbox
 type Social struct {
-	ServiceURL      string
-	ServiceKey      string
-	ServiceSecret   string
-	TwitterEnabled  bool
-	DiscordEnabled  bool
-	SlackEnabled    bool
-	TelegramEnabled bool
+	transports []Transport
+	outbox     *Outbox
 }
 
-// NewSocialChannel creates a new Social Media Connector
-func NewSocialChannel(serviceURL string, serviceKey string, serviceSecret string, twitterEnabled bool, discordEnabled bool, slackEnabled bool, telegramEnabled bool) (*Social, error) {
-	social := &Social{
-		ServiceURL:      serviceURL,
-		ServiceKey:      serviceKey,
-		ServiceSecret:   serviceSecret,
-		TwitterEnabled:  twitterEnabled,
-		DiscordEnabled:  discordEnabled,
-		SlackEnabled:    slackEnabled,
-		TelegramEnabled: telegramEnabled,
+// NewSocialChannel creates a new Social Media Connector. Undelivered messages
+// are stored in dataDir and retried up to maxAttempts times. When dedup is not
+// nil, every notification is sent at most once per platform
+func NewSocialChannel(transports []Transport, dataDir string, maxAttempts int, retryDelay time.Duration, dedup *Deduplicator) (*Social, error) {
+	if len(transports) == 0 {
+		return nil, errors.New("No social transport enabled")
 	}
 
-	url := social.ServiceURL + "/status"
-	resp, err := http.Get(url)
+	outbox, err := NewOutbox(dataDir, transports, maxAttempts, retryDelay)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, errors.New("Could not load outbox. " + err.Error())
 	}
-
-	if resp.StatusCode != 200 {
-		return nil, errors.New("Social webservice return code is not 200. Webservice url: " + url)
+	if dedup != nil {
+		outbox.SetDeduplicator(dedup)
 	}
+	go outbox.Run()
 
-	return social, nil
+	return &Social{
+		transports: transports,
+		outbox:     outbox,
+	}, nil
 }
 
-// SendMessage publishes message on enabled social medias
-func (social *Social) SendMessage(message string) error {
-	if social.DiscordEnabled {
-		err := social.sendMessageSocial(message, "discord")
-		if err != nil {
-			return err
-		}
-	}
-	if social.TwitterEnabled {
-		err := social.sendMessageSocial(message, "twitter")
-		if err != nil {
-			return err
-		}
-	}
-	if social.TelegramEnabled {
-		err := social.sendMessageSocial(message, "telegram")
-		if err != nil {
-			return err
-		}
-	}
-	if social.SlackEnabled {
-		err := social.sendMessageSocial(message, "slack")
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+// SetRateLimit limits the messages sent to a platform to perMinute messages per
+// minute with bursts of up to burst messages
+func (social *Social) SetRateLimit(platform string, perMinute float64, burst int) {
+	social.outbox.SetRateLimit(platform, perMinute, burst)
 }
 
-func (social *Social) sendMessageSocial(message string, socialMedia string) error {
-
-	url := social.ServiceURL + "/send/" + socialMedia
-	jsonStr := []byte("{\"message\":\"" + message + "\"}")
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonStr))
-	if err != nil {
-		return errors.New("Could not create post request. " + err.Error())
-	}
-	req.Header.Set("key", social.ServiceKey)
-	req.Header.Set("secret", social.ServiceSecret)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return errors.New("Could not create post request. " + err.Error())
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return errors.New("Invalid return code: " + strconv.Itoa(resp.StatusCode))
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Message sent: ", message)
-	log.Println(string(body))
-
-	return nil
+// Send queues a notification for every enabled social media
+func (social *Social) Send(notification socialevents.Notification) error {
+	return social.outbox.Enqueue(notification, social.transports)
 }
@@ -0,0 +1,146 @@
+package social
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+// postToSocialsRequest is a request received by the post-to-socials stand-in
+type postToSocialsRequest struct {
+	method      string
+	path        string
+	contentType string
+	key         string
+	secret      string
+	body        []byte
+}
+
+// newPostToSocialsServer starts a stand-in of the post-to-socials API which
+// records the requests it receives and answers with status
+func newPostToSocialsServer(t *testing.T, status int) (*httptest.Server, chan postToSocialsRequest) {
+	requests := make(chan postToSocialsRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Could not read request body: %v", err)
+		}
+		requests <- postToSocialsRequest{
+			method:      r.Method,
+			path:        r.URL.Path,
+			contentType: r.Header.Get("Content-Type"),
+			key:         r.Header.Get("key"),
+			secret:      r.Header.Get("secret"),
+			body:        body,
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestPostToSocialsSend(t *testing.T) {
+	server, requests := newPostToSocialsServer(t, http.StatusOK)
+	transport := NewPostToSocials(server.URL, "service-key", "service-secret", "discord")
+
+	message := "Market \"BTC/DAI\" \\ settled\nat 45000 🚀   </script>"
+	err := transport.Send(socialevents.Notification{
+		Message: message,
+		Images:  []string{"https://example.com/chart.png"},
+		ReplyTo: map[string]string{"discord": "1234", "twitter": "5678"},
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	request := <-requests
+	if request.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", request.method)
+	}
+	if request.path != "/send/discord" {
+		t.Errorf("path = %s, want /send/discord", request.path)
+	}
+	if request.contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", request.contentType)
+	}
+	if request.key != "service-key" || request.secret != "service-secret" {
+		t.Errorf("credentials = %q/%q, want service-key/service-secret", request.key, request.secret)
+	}
+
+	var post Post
+	err = json.Unmarshal(request.body, &post)
+	if err != nil {
+		t.Fatalf("Invalid JSON body %s: %v", request.body, err)
+	}
+	if post.Message != message {
+		t.Errorf("message = %q, want %q", post.Message, message)
+	}
+	if len(post.Images) != 1 || post.Images[0] != "https://example.com/chart.png" {
+		t.Errorf("images = %v, want the chart", post.Images)
+	}
+	if post.ReplyTo != "1234" {
+		t.Errorf("replyTo = %q, want the discord post 1234", post.ReplyTo)
+	}
+}
+
+func TestPostToSocialsSendCannotInjectFields(t *testing.T) {
+	server, requests := newPostToSocialsServer(t, http.StatusOK)
+	transport := NewPostToSocials(server.URL, "service-key", "service-secret", "slack")
+
+	message := `", "images": ["https://evil.example.com"], "replyTo": "1`
+	err := transport.Send(socialevents.Notification{Message: message})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	request := <-requests
+	var fields map[string]interface{}
+	err = json.Unmarshal(request.body, &fields)
+	if err != nil {
+		t.Fatalf("Invalid JSON body %s: %v", request.body, err)
+	}
+	if len(fields) != 1 || fields["message"] != message {
+		t.Errorf("body = %s, want only the message field", request.body)
+	}
+}
+
+func TestPostToSocialsSendTwitter(t *testing.T) {
+	server, requests := newPostToSocialsServer(t, http.StatusOK)
+	transport := NewPostToSocials(server.URL, "service-key", "service-secret", "twitter")
+
+	notification := socialevents.Notification{Message: "New market BTC/DAI"}
+	notification.AddLink("Console", "https://console.vega.xyz/markets/1")
+	err := transport.Send(notification)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	request := <-requests
+	var post Post
+	err = json.Unmarshal(request.body, &post)
+	if err != nil {
+		t.Fatalf("Invalid JSON body %s: %v", request.body, err)
+	}
+	if request.path != "/send/twitter" {
+		t.Errorf("path = %s, want /send/twitter", request.path)
+	}
+	if post.Message != renderTwitter(notification) {
+		t.Errorf("message = %q, want the twitter rendering %q", post.Message, renderTwitter(notification))
+	}
+}
+
+func TestPostToSocialsSendError(t *testing.T) {
+	server, requests := newPostToSocialsServer(t, http.StatusUnauthorized)
+	transport := NewPostToSocials(server.URL, "service-key", "wrong-secret", "telegram")
+
+	err := transport.Send(socialevents.Notification{Message: "Auction started"})
+	<-requests
+	if err == nil {
+		t.Fatal("Send succeeded, want an error on status 401")
+	}
+}
@@ -0,0 +1,48 @@
+package social
+
+import "github.com/baldator/vega-bot/socialevents"
+
+const telegramAPIURL = "https://api.telegram.org"
+
+// TelegramBot sends notifications to a Telegram chat through the Bot API
+type TelegramBot struct {
+	token  string
+	chatID string
+}
+
+type telegramPayload struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+// NewTelegramBot creates a Telegram Bot API transport
+func NewTelegramBot(token string, chatID string) *TelegramBot {
+	return &TelegramBot{
+		token:  token,
+		chatID: chatID,
+	}
+}
+
+// Name returns the transport name
+func (transport *TelegramBot) Name() string {
+	return "telegram-bot"
+}
+
+// Platform returns the social media the transport publishes on
+func (transport *TelegramBot) Platform() string {
+	return "telegram"
+}
+
+// Send publishes a notification as a MarkdownV2 message
+func (transport *TelegramBot) Send(notification socialevents.Notification) error {
+	payload := telegramPayload{
+		ChatID:    transport.chatID,
+		Text:      renderTelegramMarkdown(notification),
+		ParseMode: "MarkdownV2",
+	}
+
+	url := telegramAPIURL + "/bot" + transport.token + "/sendMessage"
+	_, err := postJSON(url, payload, nil)
+	return err
+}
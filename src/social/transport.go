@@ -0,0 +1,69 @@
+package social
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/baldator/vega-bot/socialevents"
+)
+
+// Transport delivers notifications to a social media platform
+type Transport interface {
+	// Name identifies the transport in logs
+	Name() string
+	// Platform returns the social media the transport publishes on
+	Platform() string
+	// Send renders and publishes a notification
+	Send(notification socialevents.Notification) error
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// postJSON sends payload as a JSON document and returns the response body
+func postJSON(url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	jsonStr, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.New("Could not encode message. " + err.Error())
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		return nil, errors.New("Could not create post request. " + redactURL(err).Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.New("Could not send post request. " + redactURL(err).Error())
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New("Could not read response. " + err.Error())
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return body, errors.New("Invalid return code: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return body, nil
+}
+
+// redactURL drops the URL from the request errors, it can contain secrets
+// like the Telegram bot token which must not reach the logs or the outbox
+func redactURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package socialevents
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Notification types
+const (
+	WhaleNotificationType                  = "whale"
+	WhaleTradeNotificationType             = "whale_trade"
+	RektNotificationType                   = "rekt"
+	RektCascadeNotificationType            = "rekt_cascade"
+	AuctionNotificationType                = "auction"
+	ProposalNotificationType               = "proposal"
+	ProposalProgressNotificationType       = "proposal_progress"
+	ProposalReminderNotificationType       = "proposal_reminder"
+	LossSocializationNotificationType      = "loss_socialization"
+	NetworkParameterNotificationType       = "network_parameter"
+	NetworkParameterChangeNotificationType = "network_parameter_change"
+	BridgeAddressNotificationType          = "bridge_address"
+	NetworkResetNotificationType           = "network_reset"
+	MarketCreationNotificationType         = "market_created"
+	MarketUpdateNotificationType           = "market_updated"
+	DepositNotificationType                = "deposit"
+	WithdrawalNotificationType             = "withdrawal"
+)
+
+// Severity tells how important a notification is
+type Severity int
+
+// Notification severities
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+// Field is a named value displayed in rich notifications
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Link is a titled URL attached to a notification
+type Link struct {
+	Title string
+	URL   string
+}
+
+// Notification is a message generated from a Vega bus event. Message is the
+// plain text rendering, the other fields are used by platforms supporting
+// rich messages. Value is the amount the notification is about, it is summed
+// when notifications are coalesced. A notification with an empty Message must
+// not be published. EventID is the bus event the notification was generated from,
+// aggregated notifications derive it from the events they aggregate instead.
+// ReplyTo holds, by platform, the ID of the post the notification answers
+type Notification struct {
+	EventID  string
+	Type     string
+	MarketID string
+	Market   string
+	Emoji    string
+	Title    string
+	Message  string
+	Fields   []Field
+	Links    []Link
+	Images   []string
+	Value    float64
+	Severity Severity
+	ReplyTo  map[string]string
+}
+
+// AddField appends a field to the notification
+func (notification *Notification) AddField(name string, value string) {
+	notification.Fields = append(notification.Fields, Field{Name: name, Value: value})
+}
+
+// AddLink appends a link to the notification
+func (notification *Notification) AddLink(title string, url string) {
+	notification.Links = append(notification.Links, Link{Title: title, URL: url})
+}
+
+// Key identifies the notification to detect duplicates. It is built from the
+// bus event ID, the type, the market and the content, so the same event
+// replayed gives the same key
+func (notification *Notification) Key() string {
+	hash := sha256.Sum256([]byte(notification.EventID + "\x00" + notification.Type + "\x00" + notification.MarketID + "\x00" + notification.Message))
+	return hex.EncodeToString(hash[:])
+}
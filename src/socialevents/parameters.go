@@ -0,0 +1,108 @@
+package socialevents
+
+import (
+	"strings"
+	"unicode"
+)
+
+// networkParameterDescriptions are the human descriptions of the network
+// parameters whose key does not read well
+var networkParameterDescriptions = map[string]string{
+	"blockchains.ethereumConfig":                      "Ethereum bridge configuration",
+	"governance.vote.asset":                           "Governance token",
+	"market.auction.minimumDuration":                  "Minimum auction duration",
+	"market.auction.maximumDuration":                  "Maximum auction duration",
+	"market.fee.factors.makerFee":                     "Maker fee factor of new markets",
+	"market.fee.factors.infrastructureFee":            "Infrastructure fee factor of new markets",
+	"market.liquidity.stakeToCcySiskas":               "Liquidity stake to settlement asset ratio",
+	"market.liquidity.targetstake.triggering.ratio":   "Target stake ratio triggering a liquidity auction",
+	"market.liquidity.bondPenaltyParameter":           "Liquidity bond penalty",
+	"market.liquidity.maximumLiquidityFeeFactorLevel": "Maximum liquidity fee factor",
+	"market.liquidityProvision.shapes.maxSize":        "Maximum liquidity provision shape size",
+	"market.margin.scalingFactors":                    "Default margin scaling factors",
+	"market.monitor.price.defaultParameters":          "Default price monitoring parameters",
+	"market.monitor.price.updateFrequency":            "Price monitoring update frequency",
+	"market.value.windowLength":                       "Market value window length",
+	"market.stake.target.timeWindow":                  "Target stake time window",
+	"market.stake.target.scalingFactor":               "Target stake scaling factor",
+}
+
+// governanceProposalKinds names the proposal types of the governance.proposal.* parameters
+var governanceProposalKinds = map[string]string{
+	"market":         "new market",
+	"updateMarket":   "market update",
+	"updateNetParam": "network parameter",
+	"asset":          "new asset",
+}
+
+// governanceProposalSettings names the settings of the governance.proposal.* parameters
+var governanceProposalSettings = map[string]string{
+	"minClose":              "minimum voting period",
+	"maxClose":              "maximum voting period",
+	"minEnact":              "minimum enactment delay",
+	"maxEnact":              "maximum enactment delay",
+	"requiredParticipation": "required participation",
+	"requiredMajority":      "required majority",
+	"minProposerBalance":    "minimum proposer balance",
+	"minVoterBalance":       "minimum voter balance",
+}
+
+// DescribeNetworkParameter returns a human description of a network parameter key
+func DescribeNetworkParameter(key string) string {
+	if description, ok := networkParameterDescriptions[key]; ok {
+		return description
+	}
+
+	parts := strings.Split(key, ".")
+	if len(parts) == 4 && parts[0] == "governance" && parts[1] == "proposal" {
+		kind, kindOK := governanceProposalKinds[parts[2]]
+		setting, settingOK := governanceProposalSettings[parts[3]]
+		if kindOK && settingOK {
+			return strings.ToUpper(setting[:1]) + setting[1:] + " of " + kind + " proposals"
+		}
+	}
+
+	// market.auction.minimumDuration reads market auction minimum duration
+	var words []string
+	for _, part := range parts {
+		var word []rune
+		for _, r := range part {
+			if unicode.IsUpper(r) && len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			word = append(word, unicode.ToLower(r))
+		}
+		if len(word) > 0 {
+			words = append(words, string(word))
+		}
+	}
+	description := strings.Join(words, " ")
+	if description == "" {
+		return key
+	}
+	return strings.ToUpper(description[:1]) + description[1:]
+}
+
+// NetworkParameterChangeNotification returns the message of a network
+// parameter change. previous is empty when the parameter is new
+func NetworkParameterChangeNotification(key string, previous string, value string) (Notification, error) {
+	description := DescribeNetworkParameter(key)
+	notification := Notification{
+		Type:     NetworkParameterChangeNotificationType,
+		Severity: SeverityInfo,
+	}
+	err := render(&notification, struct {
+		Key         string
+		Description string
+		Previous    string
+		New         string
+	}{key, description, previous, value})
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Parameter", key)
+	notification.AddField("Previous value", previous)
+	notification.AddField("New value", value)
+	return notification, nil
+}
@@ -3,16 +3,20 @@ package socialevents
 import (
 	"encoding/json"
 	"math"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/baldator/vega-bot/errorpolicy"
+	"github.com/baldator/vega-bot/marketcache"
+
+	"github.com/pkg/errors"
 	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
-	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
 	"golang.org/x/net/context"
 )
 
-var activeAuctions []string
-
+// EthereumConfig is the value of the blockchains.ethereumConfig network parameter
 type EthereumConfig struct {
 	NetworkID     string `json:"network_id"`
 	ChainID       string `json:"chain_id"`
@@ -20,30 +24,189 @@ type EthereumConfig struct {
 	Confirmations int    `json:"confirmations"`
 }
 
-func NetworkResetNotification(uptime string) (string, error) {
+// EthereumConfigChange is a field of the Ethereum configuration that changed
+type EthereumConfigChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+var ethereumAddress = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+
+// Validate checks that every field of the configuration is set and well formed
+func (config EthereumConfig) Validate() error {
+	if _, err := strconv.ParseUint(config.NetworkID, 10, 64); err != nil {
+		return errors.New("invalid network id " + strconv.Quote(config.NetworkID))
+	}
+	if _, err := strconv.ParseUint(config.ChainID, 10, 64); err != nil {
+		return errors.New("invalid chain id " + strconv.Quote(config.ChainID))
+	}
+	if !ethereumAddress.MatchString(config.BridgeAddress) {
+		return errors.New("invalid bridge address " + strconv.Quote(config.BridgeAddress))
+	}
+	if config.Confirmations <= 0 {
+		return errors.New("invalid confirmations " + strconv.Itoa(config.Confirmations))
+	}
+	return nil
+}
+
+// Diff returns the fields that differ from previous. Bridge addresses are
+// compared ignoring case, as checksummed and lower case addresses are equal
+func (config EthereumConfig) Diff(previous EthereumConfig) []EthereumConfigChange {
+	var changes []EthereumConfigChange
+	if config.NetworkID != previous.NetworkID {
+		changes = append(changes, EthereumConfigChange{Field: "Network id", Old: previous.NetworkID, New: config.NetworkID})
+	}
+	if config.ChainID != previous.ChainID {
+		changes = append(changes, EthereumConfigChange{Field: "Chain id", Old: previous.ChainID, New: config.ChainID})
+	}
+	if !strings.EqualFold(config.BridgeAddress, previous.BridgeAddress) {
+		changes = append(changes, EthereumConfigChange{Field: "Bridge address", Old: previous.BridgeAddress, New: config.BridgeAddress})
+	}
+	if config.Confirmations != previous.Confirmations {
+		changes = append(changes, EthereumConfigChange{Field: "Confirmations", Old: strconv.Itoa(previous.Confirmations), New: strconv.Itoa(config.Confirmations)})
+	}
+	return changes
+}
+
+// NetworkResetNotification returns network reset notification message
+func NetworkResetNotification(uptime string) (Notification, error) {
 
 	t, err := time.Parse(time.RFC3339Nano, uptime)
 	if err != nil {
-		return "", err
+		return Notification{}, errorpolicy.NewPermanent(errors.Wrap(err, "invalid network uptime"))
 	}
-	return "🔄 Vega network restarted at: " + t.Format(time.RFC822), nil
-}
 
-// MarketProposalNotification returns market proposal notification message
-func MarketProposalNotification(dataClient api.TradingDataServiceClient, marketID string, state proto.Proposal_State) (string, error) {
-	requestMarket := api.MarketByIDRequest{MarketId: marketID}
-	MarketObject, err := dataClient.MarketByID(context.Background(), &requestMarket)
+	notification := Notification{
+		Type:     NetworkResetNotificationType,
+		Severity: SeverityWarning,
+	}
+	err = render(&notification, struct{ Time time.Time }{t})
 	if err != nil {
-		return "", err
+		return Notification{}, err
 	}
+	notification.AddField("Restarted at", t.Format(time.RFC822))
+	return notification, nil
+}
 
-	Market := MarketObject.GetMarket()
-	stateString := getMarketProposalState(state)
+// ProposalChange describes the change a governance proposal makes. Kind is
+// the proposal type and Summary a one line description of the change
+type ProposalChange struct {
+	Kind    string
+	Summary string
+}
+
+// ProposalTally is the vote count of a proposal weighted by the governance
+// token balance of the voters. Yes, No and TotalSupply are token amounts,
+// the other values are ratios between 0 and 1
+type ProposalTally struct {
+	Symbol                string
+	Yes                   float64
+	No                    float64
+	TotalSupply           float64
+	Participation         float64
+	RequiredParticipation float64
+	Majority              float64
+	RequiredMajority      float64
+}
 
-	return "⚖️ Market proposal " + Market.TradableInstrument.Instrument.Name + " " + stateString, nil
+// ProposalNotification returns governance proposal notification message
+func ProposalNotification(markets *marketcache.Cache, proposal *proto.Proposal) (Notification, error) {
+	return proposalNotification(markets, ProposalNotificationType, proposal, nil, "")
+}
+
+// ProposalProgressNotification returns the message of a proposal whose tally
+// crossed its participation or majority threshold
+func ProposalProgressNotification(markets *marketcache.Cache, proposal *proto.Proposal, tally ProposalTally, progress string) (Notification, error) {
+	return proposalNotification(markets, ProposalProgressNotificationType, proposal, &tally, progress)
+}
+
+// ProposalReminderNotification returns the message of a proposal about to close
+func ProposalReminderNotification(markets *marketcache.Cache, proposal *proto.Proposal, tally ProposalTally) (Notification, error) {
+	return proposalNotification(markets, ProposalReminderNotificationType, proposal, &tally, "")
+}
+
+func proposalNotification(markets *marketcache.Cache, notificationType string, proposal *proto.Proposal, tally *ProposalTally, progress string) (Notification, error) {
+	if proposal.Terms == nil {
+		return Notification{}, errorpolicy.NewPermanent(errors.New("proposal " + proposal.Id + " has no terms"))
+	}
+
+	change := getProposalChange(markets, proposal.Terms)
+	stateString := getProposalState(proposal.State)
+	closing := time.Unix(proposal.Terms.ClosingTimestamp, 0).UTC()
+	enactment := time.Unix(proposal.Terms.EnactmentTimestamp, 0).UTC()
+
+	notification := Notification{
+		Type:     notificationType,
+		Severity: SeverityInfo,
+	}
+	// A new market gets the ID of the proposal that created it
+	if newMarket := proposal.Terms.GetNewMarket(); newMarket != nil {
+		notification.MarketID = proposal.Id
+		notification.Market = newMarket.GetChanges().GetInstrument().GetName()
+	}
+
+	err := render(&notification, struct {
+		Proposal  *proto.Proposal
+		Change    ProposalChange
+		State     string
+		Closing   time.Time
+		Enactment time.Time
+		Tally     *ProposalTally
+		Progress  string
+	}{proposal, change, stateString, closing, enactment, tally, progress})
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Proposal", change.Kind)
+	notification.AddField("Change", change.Summary)
+	notification.AddField("State", stateString)
+	notification.AddField("Proposer", shortPartyID(proposal.PartyId))
+	notification.AddField("Closing", closing.Format(time.RFC822))
+	notification.AddField("Enactment", enactment.Format(time.RFC822))
+	if tally != nil {
+		notification.AddField("Yes", formatNumber(tally.Yes)+" "+tally.Symbol)
+		notification.AddField("No", formatNumber(tally.No)+" "+tally.Symbol)
+		notification.AddField("Participation", formatPercent(tally.Participation)+" (required "+formatPercent(tally.RequiredParticipation)+")")
+		notification.AddField("Majority", formatPercent(tally.Majority)+" (required "+formatPercent(tally.RequiredMajority)+")")
+	}
+	notification.AddLink("Governance", governanceLink(proposal.Id))
+	return notification, nil
+}
+
+func getProposalChange(markets *marketcache.Cache, terms *proto.ProposalTerms) ProposalChange {
+	switch {
+	case terms.GetNewMarket() != nil:
+		instrument := terms.GetNewMarket().GetChanges().GetInstrument()
+		summary := instrument.GetName() + " (" + instrument.GetCode() + ")"
+		if future := instrument.GetFuture(); future != nil {
+			settlement := future.SettlementAsset
+			asset, err := markets.Asset(context.Background(), future.SettlementAsset)
+			if err == nil {
+				settlement = asset.Symbol
+			}
+			summary += ", future settled in " + settlement + " maturing " + future.Maturity
+		}
+		return ProposalChange{Kind: "New market", Summary: summary}
+	case terms.GetUpdateMarket() != nil:
+		return ProposalChange{Kind: "Market update", Summary: "market update"}
+	case terms.GetUpdateNetworkParameter() != nil:
+		parameter := terms.GetUpdateNetworkParameter().GetChanges()
+		return ProposalChange{Kind: "Network parameter", Summary: parameter.GetKey() + " = " + parameter.GetValue()}
+	case terms.GetNewAsset() != nil:
+		source := terms.GetNewAsset().GetChanges()
+		if builtin := source.GetBuiltinAsset(); builtin != nil {
+			return ProposalChange{Kind: "New asset", Summary: builtin.Name + " (" + builtin.Symbol + ")"}
+		}
+		if erc20 := source.GetErc20(); erc20 != nil {
+			return ProposalChange{Kind: "New asset", Summary: "ERC20 token " + erc20.ContractAddress}
+		}
+		return ProposalChange{Kind: "New asset", Summary: "unknown asset source"}
+	}
+	return ProposalChange{Kind: "Unknown", Summary: "unknown change"}
 }
 
-func getMarketProposalState(state proto.Proposal_State) string {
+func getProposalState(state proto.Proposal_State) string {
 	var stateString string
 	switch state {
 	case proto.Proposal_STATE_UNSPECIFIED:
@@ -66,41 +229,54 @@ func getMarketProposalState(state proto.Proposal_State) string {
 	return stateString
 }
 
+// AuctionDetails describes an auction status change. Status is started,
+// extended or ended. Prices are expressed in market decimal places
+type AuctionDetails struct {
+	Status           string
+	Duration         time.Duration
+	IndicativePrice  uint64
+	IndicativeVolume uint64
+}
+
 // AuctionNotification returns auction notification message
-func AuctionNotification(dataClient api.TradingDataServiceClient, auction *proto.AuctionEvent, excludeExtend bool) (string, error) {
-	market, err := getMarketByID(dataClient, auction.MarketId)
+func AuctionNotification(markets *marketcache.Cache, auction *proto.AuctionEvent, details AuctionDetails) (Notification, error) {
+	market, err := getMarketByID(markets, auction.MarketId)
 	if err != nil {
-		return "", err
-	}
-
-	status := "started"
-	if !auction.Leave {
-		for _, v := range activeAuctions {
-			if v == auction.MarketId {
-				if !excludeExtend {
-					return "", nil
-				}
-				status = "extended"
-				break
-			}
-		}
-		activeAuctions = append(activeAuctions, auction.MarketId)
-	}
-
-	if auction.Leave {
-		status = "ended"
-		for i, v := range activeAuctions {
-			if v == auction.MarketId {
-				activeAuctions = append(activeAuctions[:i], activeAuctions[i+1:]...)
-				break
-			}
-		}
+		return Notification{}, err
 	}
 
 	auctionType := getAuctionType(auction.Trigger)
-	message := "🔨 " + auctionType + " on " + market.TradableInstrument.Instrument.Name + " has " + status
+	name := market.TradableInstrument.Instrument.Name
 
-	return message, nil
+	notification := Notification{
+		Type:     AuctionNotificationType,
+		MarketID: auction.MarketId,
+		Market:   name,
+		Severity: SeverityInfo,
+	}
+	err = render(&notification, struct {
+		Market      *proto.Market
+		Auction     *proto.AuctionEvent
+		AuctionType string
+		Status      string
+		Details     AuctionDetails
+	}{market, auction, auctionType, details.Status, details})
+	if err != nil {
+		return Notification{}, err
+	}
+	if auction.Trigger == proto.AuctionTrigger_AUCTION_TRIGGER_PRICE || auction.Trigger == proto.AuctionTrigger_AUCTION_TRIGGER_LIQUIDITY {
+		notification.Severity = SeverityWarning
+	}
+	notification.AddField("Market", name)
+	notification.AddField("Status", details.Status)
+	if details.Duration > 0 {
+		notification.AddField("Duration", details.Duration.String())
+	}
+	if details.IndicativeVolume > 0 {
+		notification.AddField("Indicative price", formatDecimal(details.IndicativePrice, market.DecimalPlaces))
+		notification.AddField("Indicative volume", strconv.FormatUint(details.IndicativeVolume, 10))
+	}
+	return notification, nil
 }
 
 func getAuctionType(trigger proto.AuctionTrigger) string {
@@ -121,76 +297,544 @@ func getAuctionType(trigger proto.AuctionTrigger) string {
 	return auctionType
 }
 
-// NetworkParametesNotification returns network notification message
-func NetworkParametesNotification(dataClient api.TradingDataServiceClient, network *proto.NetworkParameter, current *proto.NetworkParameter) string {
-	var currentConfig EthereumConfig
-	var newConfig EthereumConfig
-	message := ""
+// ParseEthereumConfig decodes and validates the value of the
+// blockchains.ethereumConfig network parameter
+func ParseEthereumConfig(value string) (EthereumConfig, error) {
+	var config EthereumConfig
+	err := json.Unmarshal([]byte(value), &config)
+	if err != nil {
+		return EthereumConfig{}, errors.Wrap(err, "invalid Ethereum config")
+	}
+	err = config.Validate()
+	if err != nil {
+		return EthereumConfig{}, errors.Wrap(err, "invalid Ethereum config")
+	}
+	return config, nil
+}
+
+// CollateralMovement is a deposit or a withdrawal, or several small ones of
+// the same party and asset. Amount is expressed in asset units. TxLink is the
+// block explorer link of the Ethereum transaction, or of the bridge contract
+// when the transaction is unknown
+type CollateralMovement struct {
+	PartyID string
+	Asset   *proto.Asset
+	Amount  float64
+	Count   int
+	TxHash  string
+	TxLink  string
+}
+
+// DepositNotification returns deposit notification message
+func DepositNotification(movement CollateralMovement) (Notification, error) {
+	return collateralNotification(DepositNotificationType, movement)
+}
+
+// WithdrawalNotification returns withdrawal notification message
+func WithdrawalNotification(movement CollateralMovement) (Notification, error) {
+	return collateralNotification(WithdrawalNotificationType, movement)
+}
+
+func collateralNotification(notificationType string, movement CollateralMovement) (Notification, error) {
+	amount := formatAsset(movement.Amount, movement.Asset)
+	notification := Notification{
+		Type:     notificationType,
+		Value:    movement.Amount,
+		Severity: SeverityInfo,
+	}
+	err := render(&notification, struct {
+		Movement CollateralMovement
+		Amount   string
+	}{movement, amount})
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Amount", amount)
+	notification.AddField("Party", shortPartyID(movement.PartyID))
+	if movement.Count > 1 {
+		notification.AddField("Transactions", strconv.Itoa(movement.Count))
+	}
+	if movement.TxLink != "" {
+		notification.AddLink("Ethereum", movement.TxLink)
+	}
+	return notification, nil
+}
+
+// NetworkParametesNotification returns the message of an Ethereum
+// configuration change, with an empty Message when nothing changed. A bridge
+// address change raises a critical bridge_address alert. Both configurations
+// must be valid
+func NetworkParametesNotification(network *proto.NetworkParameter, current *proto.NetworkParameter) (Notification, error) {
+	if network == nil || current == nil {
+		return Notification{}, nil
+	}
+
+	newConfig, err := ParseEthereumConfig(network.Value)
+	if err != nil {
+		return Notification{}, errorpolicy.NewPermanent(err)
+	}
+	currentConfig, err := ParseEthereumConfig(current.Value)
+	if err != nil {
+		return Notification{}, errorpolicy.NewPermanent(errors.Wrap(err, "previous"))
+	}
+
+	changes := newConfig.Diff(currentConfig)
+	if len(changes) == 0 {
+		return Notification{}, nil
+	}
+
+	notification := Notification{
+		Type:     NetworkParameterNotificationType,
+		Severity: SeverityWarning,
+	}
+	if !strings.EqualFold(newConfig.BridgeAddress, currentConfig.BridgeAddress) {
+		notification.Type = BridgeAddressNotificationType
+		notification.Severity = SeverityCritical
+	}
+	err = render(&notification, struct {
+		Previous EthereumConfig
+		New      EthereumConfig
+		Changes  []EthereumConfigChange
+	}{currentConfig, newConfig, changes})
+	if err != nil {
+		return Notification{}, err
+	}
+	for _, change := range changes {
+		notification.AddField(change.Field, change.Old+" → "+change.New)
+	}
+	return notification, nil
+}
+
+// MarketDetails describes the configuration of a market
+type MarketDetails struct {
+	Instrument      string
+	Code            string
+	Product         string
+	SettlementAsset string
+	Maturity        string
+	DecimalPlaces   uint64
+	RiskModel       string
+	PriceMonitoring string
+	OpeningAuction  time.Duration
+}
 
-	json.Unmarshal([]byte(current.Value), &currentConfig)
-	json.Unmarshal([]byte(network.Value), &newConfig)
+// MarketChange is a market configuration value that changed
+type MarketChange struct {
+	Name string
+	Old  string
+	New  string
+}
 
-	if currentConfig.NetworkID != newConfig.NetworkID {
-		message = "🔄 Ethereum network parameter changed. New network id is: " + newConfig.NetworkID
+// NewMarketDetails describes a market. The settlement asset is named by its
+// symbol when the asset is known
+func NewMarketDetails(markets *marketcache.Cache, market *proto.Market) MarketDetails {
+	instrument := market.GetTradableInstrument().GetInstrument()
+	details := MarketDetails{
+		Instrument:      instrument.GetName(),
+		Code:            instrument.GetCode(),
+		Product:         "unknown",
+		DecimalPlaces:   market.DecimalPlaces,
+		RiskModel:       getRiskModel(market.GetTradableInstrument()),
+		PriceMonitoring: getPriceMonitoring(market.GetPriceMonitoringSettings()),
+		OpeningAuction:  time.Duration(market.GetOpeningAuction().GetDuration()) * time.Second,
 	}
+	if future := instrument.GetFuture(); future != nil {
+		details.Product = "future"
+		details.Maturity = future.Maturity
+		details.SettlementAsset = future.SettlementAsset
+		asset, err := markets.Asset(context.Background(), future.SettlementAsset)
+		if err == nil {
+			details.SettlementAsset = asset.Symbol
+		}
+	}
+	return details
+}
 
-	return message
+// Fields returns the market details as named values
+func (details MarketDetails) Fields() []Field {
+	return []Field{
+		{Name: "Instrument", Value: details.Instrument},
+		{Name: "Code", Value: details.Code},
+		{Name: "Product", Value: details.Product},
+		{Name: "Settlement asset", Value: details.SettlementAsset},
+		{Name: "Maturity", Value: details.Maturity},
+		{Name: "Decimal places", Value: strconv.FormatUint(details.DecimalPlaces, 10)},
+		{Name: "Risk model", Value: details.RiskModel},
+		{Name: "Price monitoring", Value: details.PriceMonitoring},
+		{Name: "Opening auction", Value: details.OpeningAuction.String()},
+	}
+}
+
+// Changes returns the details that differ from previous
+func (details MarketDetails) Changes(previous MarketDetails) []MarketChange {
+	var changes []MarketChange
+	old := previous.Fields()
+	for i, field := range details.Fields() {
+		if field.Value != old[i].Value {
+			changes = append(changes, MarketChange{Name: field.Name, Old: old[i].Value, New: field.Value})
+		}
+	}
+	return changes
 }
 
 // MarketCreationNotification returns market creation notification message
-func MarketCreationNotification(dataClient api.TradingDataServiceClient, market *proto.Market) (string, error) {
-	return "⚖️ A new market created for " + market.TradableInstrument.Instrument.Name, nil
+func MarketCreationNotification(markets *marketcache.Cache, market *proto.Market) (Notification, error) {
+	details := NewMarketDetails(markets, market)
+	notification := Notification{
+		Type:     MarketCreationNotificationType,
+		MarketID: market.Id,
+		Market:   details.Instrument,
+		Severity: SeverityInfo,
+	}
+	err := render(&notification, struct {
+		Market  *proto.Market
+		Details MarketDetails
+	}{market, details})
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.Fields = append(notification.Fields, details.Fields()...)
+	return notification, nil
+}
+
+// MarketUpdateNotification returns market update notification message
+func MarketUpdateNotification(markets *marketcache.Cache, market *proto.Market, changes []MarketChange) (Notification, error) {
+	details := NewMarketDetails(markets, market)
+	notification := Notification{
+		Type:     MarketUpdateNotificationType,
+		MarketID: market.Id,
+		Market:   details.Instrument,
+		Severity: SeverityInfo,
+	}
+	err := render(&notification, struct {
+		Market  *proto.Market
+		Details MarketDetails
+		Changes []MarketChange
+	}{market, details, changes})
+	if err != nil {
+		return Notification{}, err
+	}
+	for _, change := range changes {
+		notification.AddField(change.Name, change.Old+" → "+change.New)
+	}
+	return notification, nil
+}
+
+func getRiskModel(instrument *proto.TradableInstrument) string {
+	if logNormal := instrument.GetLogNormalRiskModel(); logNormal != nil {
+		params := logNormal.GetParams()
+		return "log-normal (risk aversion " + formatNumber(logNormal.RiskAversionParameter) +
+			", tau " + formatNumber(logNormal.Tau) +
+			", mu " + formatNumber(params.GetMu()) +
+			", r " + formatNumber(params.GetR()) +
+			", sigma " + formatNumber(params.GetSigma()) + ")"
+	}
+	if simple := instrument.GetSimpleRiskModel(); simple != nil {
+		params := simple.GetParams()
+		return "simple (factor long " + formatNumber(params.GetFactorLong()) +
+			", factor short " + formatNumber(params.GetFactorShort()) + ")"
+	}
+	return "unknown"
+}
+
+func getPriceMonitoring(settings *proto.PriceMonitoringSettings) string {
+	triggers := settings.GetParameters().GetTriggers()
+	if len(triggers) == 0 {
+		return "none"
+	}
+
+	bounds := make([]string, 0, len(triggers))
+	for _, trigger := range triggers {
+		bounds = append(bounds, "horizon "+(time.Duration(trigger.Horizon)*time.Second).String()+
+			", probability "+formatNumber(trigger.Probability)+
+			", auction extension "+(time.Duration(trigger.AuctionExtension)*time.Second).String())
+	}
+	return strings.Join(bounds, "; ")
 }
 
 // LossSocializationNotification returns loss socialization notification message
-func LossSocializationNotification(dataClient api.TradingDataServiceClient, lossSocialization *proto.LossSocialization) (string, error) {
-	market, err := getMarketByID(dataClient, lossSocialization.MarketId)
+func LossSocializationNotification(markets *marketcache.Cache, lossSocialization *proto.LossSocialization) (Notification, error) {
+	market, err := getMarketByID(markets, lossSocialization.MarketId)
 	if err != nil {
-		return "", err
+		return Notification{}, err
 	}
 
 	decimal := float64(market.GetDecimalPlaces())
 	value := float64(lossSocialization.Amount) / (math.Pow(10, decimal))
 	value = math.Abs(value)
+	amount := strconv.FormatFloat(value, 'f', -1, 64)
+	name := market.TradableInstrument.Instrument.Name
+
+	notification := Notification{
+		Type:     LossSocializationNotificationType,
+		MarketID: lossSocialization.MarketId,
+		Market:   name,
+		Value:    value,
+		Severity: SeverityWarning,
+	}
+	err = render(&notification, struct {
+		Market            *proto.Market
+		LossSocialization *proto.LossSocialization
+		Amount            float64
+	}{market, lossSocialization, value})
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Market", name)
+	notification.AddField("Amount distributed", amount)
+	return notification, nil
+}
 
-	message := "💰 Loss socialization on " + market.TradableInstrument.Instrument.Name + ". Amount distributed: " + strconv.FormatFloat(value, 'f', -1, 64)
-	return message, nil
+// RektCascade summarises the close outs of a market within a short time.
+// Prices are expressed in market decimal places, Notional in settlement asset
+// units and LossSocialized in settlement asset decimal places
+type RektCascade struct {
+	MarketID string
+	// Type is TYPE_NETWORK_CLOSE_OUT_BAD when at least one close out was bad
+	Type           proto.Trade_Type
+	Count          int
+	Size           uint64
+	Notional       float64
+	LongSize       uint64
+	ShortSize      uint64
+	LowPrice       uint64
+	HighPrice      uint64
+	MarkPrice      uint64
+	Parties        []string
+	LossSocialized uint64
 }
 
-// RektNotification returns rekt notification message
-func RektNotification(dataClient api.TradingDataServiceClient, trade *proto.Trade) (string, error) {
-	market, err := getMarketByID(dataClient, trade.MarketId)
+// rektData is the template data of rekt notifications
+type rektData struct {
+	Market         *proto.Market
+	Asset          *proto.Asset
+	Trade          *proto.Trade
+	Cascade        RektCascade
+	Direction      string
+	CloseOut       string
+	Notional       string
+	LossSocialized string
+}
+
+func newRektData(markets *marketcache.Cache, trade *proto.Trade, cascade RektCascade) (rektData, error) {
+	market, err := getMarketByID(markets, cascade.MarketID)
+	if err != nil {
+		return rektData{}, err
+	}
+	asset, err := markets.Asset(context.Background(), market.TradableInstrument.Instrument.GetFuture().GetSettlementAsset())
 	if err != nil {
-		return "", err
+		return rektData{}, err
 	}
 
-	decimal := float64(market.GetDecimalPlaces())
-	value := float64(trade.Price) / (math.Pow(10, decimal))
+	data := rektData{
+		Market:    market,
+		Asset:     asset,
+		Trade:     trade,
+		Cascade:   cascade,
+		Direction: getWipedSide(cascade),
+		CloseOut:  "bad",
+		Notional:  formatAsset(cascade.Notional, asset),
+	}
+	if cascade.Type == proto.Trade_TYPE_NETWORK_CLOSE_OUT_GOOD {
+		data.CloseOut = "good"
+	}
+	if cascade.LossSocialized > 0 {
+		data.LossSocialized = formatAsset(float64(cascade.LossSocialized)/math.Pow(10, float64(asset.Decimals)), asset)
+	}
+	return data, nil
+}
+
+func (data rektData) addFields(notification *Notification) {
+	notification.AddField("Direction", data.Direction+" wiped")
+	notification.AddField("Close out", data.CloseOut)
+	notification.AddField("Notional", data.Notional)
+	for _, party := range data.Cascade.Parties {
+		notification.AddField("Distressed party", shortPartyID(party))
+	}
+	if data.LossSocialized != "" {
+		notification.AddField("Loss socialised", data.LossSocialized)
+	}
+}
+
+// RektNotification returns rekt notification message for a single close out
+func RektNotification(markets *marketcache.Cache, trade *proto.Trade, cascade RektCascade) (Notification, error) {
+	data, err := newRektData(markets, trade, cascade)
+	if err != nil {
+		return Notification{}, err
+	}
 
-	message := " 💸 A position on " + market.TradableInstrument.Instrument.Name + " has been liquidated. Position size: " + strconv.FormatUint(trade.Size, 10) + ", position price: " + strconv.FormatFloat(value, 'f', -1, 64)
-	return message, nil
+	decimal := float64(data.Market.GetDecimalPlaces())
+	value := float64(trade.Price) / (math.Pow(10, decimal))
+	size := strconv.FormatUint(trade.Size, 10)
+	price := strconv.FormatFloat(value, 'f', -1, 64)
+
+	notification := Notification{
+		Type:     RektNotificationType,
+		MarketID: trade.MarketId,
+		Market:   data.Market.TradableInstrument.Instrument.Name,
+		Value:    cascade.Notional,
+		Severity: SeverityCritical,
+	}
+	if cascade.Type == proto.Trade_TYPE_NETWORK_CLOSE_OUT_GOOD {
+		notification.Severity = SeverityWarning
+	}
+	err = render(&notification, data)
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Position size", size)
+	notification.AddField("Position price", price)
+	data.addFields(&notification)
+	return notification, nil
 }
 
-func getMarketByID(dataClient api.TradingDataServiceClient, marketID string) (*proto.Market, error) {
-	requestMarket := api.MarketByIDRequest{MarketId: marketID}
-	MarketObject, err := dataClient.MarketByID(context.Background(), &requestMarket)
+// RektCascadeNotification returns liquidation cascade notification message
+func RektCascadeNotification(markets *marketcache.Cache, cascade RektCascade) (Notification, error) {
+	data, err := newRektData(markets, nil, cascade)
 	if err != nil {
-		return nil, err
+		return Notification{}, err
+	}
+	decimal := math.Pow(10, float64(data.Market.GetDecimalPlaces()))
+	formatPrice := func(price uint64) string {
+		return strconv.FormatFloat(float64(price)/decimal, 'f', -1, 64)
+	}
+
+	notification := Notification{
+		Type:     RektCascadeNotificationType,
+		MarketID: cascade.MarketID,
+		Market:   data.Market.TradableInstrument.Instrument.Name,
+		Value:    cascade.Notional,
+		Severity: SeverityCritical,
+	}
+	err = render(&notification, data)
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Positions closed", strconv.Itoa(cascade.Count))
+	notification.AddField("Total size", strconv.FormatUint(cascade.Size, 10))
+	notification.AddField("Price range", formatPrice(cascade.LowPrice)+" - "+formatPrice(cascade.HighPrice))
+	notification.AddField("Mark price", formatPrice(cascade.MarkPrice))
+	data.addFields(&notification)
+	return notification, nil
+}
+
+func getWipedSide(cascade RektCascade) string {
+	switch {
+	case cascade.LongSize > 0 && cascade.ShortSize > 0:
+		return "longs and shorts"
+	case cascade.ShortSize > 0:
+		return "shorts"
 	}
+	return "longs"
+}
 
-	return MarketObject.GetMarket(), nil
+func getMarketByID(markets *marketcache.Cache, marketID string) (*proto.Market, error) {
+	return markets.Market(context.Background(), marketID)
 }
 
-// WhaleNotification return whale notification message
-func WhaleNotification(dataClient api.TradingDataServiceClient, order *proto.Order) (string, error) {
-	market, err := getMarketByID(dataClient, order.MarketId)
+// WhaleNotification return whale notification message. share is the part of
+// the market depth represented by the order
+func WhaleNotification(markets *marketcache.Cache, order *proto.Order, share float64) (Notification, error) {
+	market, err := getMarketByID(markets, order.MarketId)
 	if err != nil {
-		return "", err
+		return Notification{}, err
 	}
 	var value float64
 	decimal := float64(market.GetDecimalPlaces())
 	value = (float64(order.Size) * float64(order.Price)) / (math.Pow(10, decimal))
-	message := "🐋 Whale alert on " + market.TradableInstrument.Instrument.Name + ". order value: " + strconv.FormatFloat(value, 'f', -1, 64)
+	orderValue := strconv.FormatFloat(value, 'f', -1, 64)
+	name := market.TradableInstrument.Instrument.Name
+
+	notification := Notification{
+		Type:     WhaleNotificationType,
+		MarketID: order.MarketId,
+		Market:   name,
+		Value:    value,
+		Severity: SeverityWarning,
+	}
+	err = render(&notification, struct {
+		Market *proto.Market
+		Order  *proto.Order
+		Value  float64
+		Share  float64
+	}{market, order, value, share})
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Side", getSideName(order.Side))
+	notification.AddField("Order value", orderValue)
+	if share > 0 {
+		notification.AddField("Share of depth", strconv.FormatFloat(share*100, 'f', 1, 64)+"%")
+	}
+	return notification, nil
+}
+
+// WhaleTrade is the aggregation of the trades of an aggressive order. Prices
+// are expressed in market decimal places, PreTradeMid is 0 when unknown
+type WhaleTrade struct {
+	MarketID       string
+	OrderID        string
+	PartyID        string
+	Side           proto.Side
+	Size           uint64
+	VWAP           float64
+	LastPrice      uint64
+	PreTradeMid    float64
+	Counterparties int
+}
+
+// WhaleTradeNotification returns whale trade notification message
+func WhaleTradeNotification(markets *marketcache.Cache, trade WhaleTrade) (Notification, error) {
+	market, err := getMarketByID(markets, trade.MarketID)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	decimal := math.Pow(10, float64(market.GetDecimalPlaces()))
+	vwap := trade.VWAP / decimal
+	value := float64(trade.Size) * vwap
+	side := getSideName(trade.Side)
+	// price impact of the last fill versus the mid price before the order traded
+	var impact float64
+	if trade.PreTradeMid > 0 {
+		impact = (float64(trade.LastPrice) - trade.PreTradeMid) / trade.PreTradeMid * 100
+	}
 
-	return message, nil
+	notification := Notification{
+		Type:     WhaleTradeNotificationType,
+		MarketID: trade.MarketID,
+		Market:   market.TradableInstrument.Instrument.Name,
+		Value:    value,
+		Severity: SeverityWarning,
+	}
+	err = render(&notification, struct {
+		Market *proto.Market
+		Trade  WhaleTrade
+		Side   string
+		VWAP   float64
+		Value  float64
+		Impact float64
+	}{market, trade, side, vwap, value, impact})
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.AddField("Aggressor side", side)
+	notification.AddField("Total size", strconv.FormatUint(trade.Size, 10))
+	notification.AddField("VWAP", strconv.FormatFloat(vwap, 'f', -1, 64))
+	notification.AddField("Trade value", strconv.FormatFloat(value, 'f', -1, 64))
+	notification.AddField("Counterparties", strconv.Itoa(trade.Counterparties))
+	if trade.PreTradeMid > 0 {
+		notification.AddField("Price impact", strconv.FormatFloat(impact, 'f', 2, 64)+"%")
+	}
+	return notification, nil
+}
+
+func getSideName(side proto.Side) string {
+	switch side {
+	case proto.Side_SIDE_BUY:
+		return "buy"
+	case proto.Side_SIDE_SELL:
+		return "sell"
+	}
+	return "undefined"
 }
@@ -0,0 +1,235 @@
+package socialevents
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/baldator/vega-bot/errorpolicy"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+)
+
+// Every notification type has three templates: <type>.emoji, <type>.title and
+// <type>.message. Files with the .tmpl extension in the templates directory
+// can redefine any of them with {{define "<type>.<part>"}}...{{end}}
+const defaultTemplates = `
+{{define "whale.emoji"}}🐋{{end}}
+{{define "whale.title"}}Whale alert on {{marketName .Market}}{{end}}
+{{define "whale.message"}}🐋 Whale alert on {{marketName .Market}}. order value: {{number .Value}}{{end}}
+
+{{define "whale_trade.emoji"}}🐳{{end}}
+{{define "whale_trade.title"}}Whale trade on {{marketName .Market}}{{end}}
+{{define "whale_trade.message"}}🐳 Whale trade on {{marketName .Market}}. {{.Side}} {{.Trade.Size}} at an average price of {{number .VWAP}} against {{.Trade.Counterparties}} counterparties, trade value: {{number .Value}}{{if .Trade.PreTradeMid}}, price impact: {{printf "%.2f" .Impact}}%{{end}}{{end}}
+
+{{define "rekt.emoji"}}💸{{end}}
+{{define "rekt.title"}}Position liquidated on {{marketName .Market}}{{end}}
+{{define "rekt.message"}} 💸 A position on {{marketName .Market}} has been liquidated. Position size: {{.Trade.Size}}, position price: {{decimal .Trade.Price .Market.DecimalPlaces}}, notional: {{.Notional}}, {{.Direction}} wiped ({{.CloseOut}} close out){{range .Cascade.Parties}}, party: {{shortParty .}}{{end}}{{if .LossSocialized}}, loss socialised: {{.LossSocialized}}{{end}}{{end}}
+
+{{define "rekt_cascade.emoji"}}🌊{{end}}
+{{define "rekt_cascade.title"}}Liquidation cascade on {{marketName .Market}}{{end}}
+{{define "rekt_cascade.message"}}🌊 Liquidation cascade on {{marketName .Market}}. {{.Cascade.Count}} positions closed, {{.Direction}} wiped, total size: {{.Cascade.Size}}, notional: {{.Notional}}, price range: {{decimal .Cascade.LowPrice .Market.DecimalPlaces}} - {{decimal .Cascade.HighPrice .Market.DecimalPlaces}}, mark price: {{decimal .Cascade.MarkPrice .Market.DecimalPlaces}}{{if .LossSocialized}}, loss socialised: {{.LossSocialized}}{{end}}{{end}}
+
+{{define "auction.emoji"}}🔨{{end}}
+{{define "auction.title"}}{{.AuctionType}} {{.Status}}{{end}}
+{{define "auction.message"}}🔨 {{.AuctionType}} on {{marketName .Market}} has {{.Status}}{{if .Details.Duration}} after {{.Details.Duration}}{{end}}{{if .Details.IndicativeVolume}}. Indicative price: {{decimal .Details.IndicativePrice .Market.DecimalPlaces}}, indicative volume: {{.Details.IndicativeVolume}}{{end}}{{end}}
+
+{{define "proposal.emoji"}}⚖️{{end}}
+{{define "proposal.title"}}{{.Change.Kind}} proposal {{.State}}{{end}}
+{{define "proposal.message"}}⚖️ {{.Change.Kind}} proposal {{.State}}: {{.Change.Summary}}. Proposed by {{shortParty .Proposal.PartyId}}, voting closes {{.Closing.Format "02 Jan 06 15:04 MST"}}, enactment {{.Enactment.Format "02 Jan 06 15:04 MST"}}.{{end}}
+
+{{define "proposal_progress.emoji"}}🗳️{{end}}
+{{define "proposal_progress.title"}}{{.Change.Kind}} proposal {{.Progress}}{{end}}
+{{define "proposal_progress.message"}}🗳️ {{.Change.Kind}} proposal {{.Change.Summary}}: {{.Progress}}. Yes: {{number .Tally.Yes}} {{.Tally.Symbol}}, no: {{number .Tally.No}} {{.Tally.Symbol}}, participation: {{percent .Tally.Participation}} (required {{percent .Tally.RequiredParticipation}}), majority: {{percent .Tally.Majority}} (required {{percent .Tally.RequiredMajority}}).{{end}}
+
+{{define "proposal_reminder.emoji"}}⏰{{end}}
+{{define "proposal_reminder.title"}}{{.Change.Kind}} proposal closing soon{{end}}
+{{define "proposal_reminder.message"}}⏰ Voting on {{.Change.Kind}} proposal {{.Change.Summary}} closes {{.Closing.Format "02 Jan 06 15:04 MST"}}. Yes: {{number .Tally.Yes}} {{.Tally.Symbol}}, no: {{number .Tally.No}} {{.Tally.Symbol}}, participation: {{percent .Tally.Participation}} (required {{percent .Tally.RequiredParticipation}}), majority: {{percent .Tally.Majority}} (required {{percent .Tally.RequiredMajority}}).{{end}}
+
+{{define "loss_socialization.emoji"}}💰{{end}}
+{{define "loss_socialization.title"}}Loss socialization on {{marketName .Market}}{{end}}
+{{define "loss_socialization.message"}}💰 Loss socialization on {{marketName .Market}}. Amount distributed: {{number .Amount}}{{end}}
+
+{{define "deposit.emoji"}}🏦{{end}}
+{{define "deposit.title"}}Large deposit of {{.Movement.Asset.Symbol}}{{end}}
+{{define "deposit.message"}}🏦 {{if gt .Movement.Count 1}}{{.Movement.Count}} deposits totalling{{else}}Deposit of{{end}} {{.Amount}} by {{shortParty .Movement.PartyID}}{{end}}
+
+{{define "withdrawal.emoji"}}🏧{{end}}
+{{define "withdrawal.title"}}Large withdrawal of {{.Movement.Asset.Symbol}}{{end}}
+{{define "withdrawal.message"}}🏧 {{if gt .Movement.Count 1}}{{.Movement.Count}} withdrawals totalling{{else}}Withdrawal of{{end}} {{.Amount}} by {{shortParty .Movement.PartyID}}{{end}}
+
+{{define "network_parameter.emoji"}}🔄{{end}}
+{{define "network_parameter.title"}}Ethereum network parameter changed{{end}}
+{{define "network_parameter.message"}}🔄 Ethereum network parameter changed. {{range $i, $change := .Changes}}{{if $i}}, {{end}}{{$change.Field}}: {{$change.Old}} → {{$change.New}}{{end}}{{end}}
+
+{{define "bridge_address.emoji"}}🚨{{end}}
+{{define "bridge_address.title"}}Ethereum bridge address changed{{end}}
+{{define "bridge_address.message"}}🚨 The Vega Ethereum bridge contract changed from {{.Previous.BridgeAddress}} to {{.New.BridgeAddress}}. Make sure to deposit to the new bridge address only. {{range $i, $change := .Changes}}{{if $i}}, {{end}}{{$change.Field}}: {{$change.Old}} → {{$change.New}}{{end}}{{end}}
+
+{{define "network_parameter_change.emoji"}}🔧{{end}}
+{{define "network_parameter_change.title"}}{{.Description}} changed{{end}}
+{{define "network_parameter_change.message"}}🔧 {{.Description}} ({{.Key}}) {{if .Previous}}changed: {{.Previous}} → {{.New}}{{else}}set to {{.New}}{{end}}{{end}}
+
+{{define "network_reset.emoji"}}🔄{{end}}
+{{define "network_reset.title"}}Vega network restarted{{end}}
+{{define "network_reset.message"}}🔄 Vega network restarted at: {{.Time.Format "02 Jan 06 15:04 MST"}}{{end}}
+
+{{define "market_created.emoji"}}⚖️{{end}}
+{{define "market_created.title"}}New market created{{end}}
+{{define "market_created.message"}}⚖️ A new market created for {{marketName .Market}} ({{.Details.Code}}). Product: {{.Details.Product}}, settlement asset: {{.Details.SettlementAsset}}{{if .Details.Maturity}}, maturity: {{.Details.Maturity}}{{end}}, decimal places: {{.Details.DecimalPlaces}}, risk model: {{.Details.RiskModel}}, price monitoring: {{.Details.PriceMonitoring}}, opening auction: {{.Details.OpeningAuction}}{{end}}
+
+{{define "market_updated.emoji"}}🛠️{{end}}
+{{define "market_updated.title"}}Market {{marketName .Market}} updated{{end}}
+{{define "market_updated.message"}}🛠️ Market {{marketName .Market}} ({{.Details.Code}}) updated. {{range $i, $change := .Changes}}{{if $i}}, {{end}}{{$change.Name}}: {{$change.Old}} → {{$change.New}}{{end}}{{end}}
+`
+
+var (
+	templates     = template.Must(newTemplates())
+	explorerURL   = "https://explorer.vega.xyz"
+	governanceURL = "https://token.vega.xyz/governance"
+)
+
+var templateFuncs = template.FuncMap{
+	"decimal":        formatDecimal,
+	"number":         formatNumber,
+	"percent":        formatPercent,
+	"marketName":     marketName,
+	"marketCode":     marketCode,
+	"shortParty":     shortPartyID,
+	"explorerLink":   explorerLink,
+	"governanceLink": governanceLink,
+}
+
+func newTemplates() (*template.Template, error) {
+	return template.New("notifications").Funcs(templateFuncs).Parse(defaultTemplates)
+}
+
+// LoadTemplates replaces the built-in templates with the ones defined in the
+// .tmpl files of dir. Templates not redefined keep their default. explorer is
+// the block explorer base URL used by the explorerLink helper, governance the
+// governance page base URL used by the governanceLink helper
+func LoadTemplates(dir string, explorer string, governance string) error {
+	if explorer != "" {
+		explorerURL = strings.TrimRight(explorer, "/")
+	}
+	if governance != "" {
+		governanceURL = strings.TrimRight(governance, "/")
+	}
+
+	loaded, err := newTemplates()
+	if err != nil {
+		return err
+	}
+
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return errors.Wrap(err, "invalid templates directory")
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			loaded, err = loaded.ParseFiles(files...)
+			if err != nil {
+				return errors.Wrap(err, "could not parse templates")
+			}
+		}
+	}
+
+	templates = loaded
+	return nil
+}
+
+// render fills the emoji, title and message of a notification from the
+// templates of its type
+func render(notification *Notification, data interface{}) error {
+	parts := []*string{&notification.Emoji, &notification.Title, &notification.Message}
+	for i, part := range []string{"emoji", "title", "message"} {
+		var buffer bytes.Buffer
+		err := templates.ExecuteTemplate(&buffer, notification.Type+"."+part, data)
+		if err != nil {
+			return errorpolicy.NewPermanent(errors.Wrap(err, "could not render "+notification.Type+" notification"))
+		}
+		*parts[i] = buffer.String()
+	}
+
+	return nil
+}
+
+// formatDecimal formats an integer amount expressed with the given number of decimal places
+func formatDecimal(value interface{}, decimalPlaces uint64) string {
+	var amount float64
+	switch v := value.(type) {
+	case uint64:
+		amount = float64(v)
+	case int64:
+		amount = float64(v)
+	case int:
+		amount = float64(v)
+	case float64:
+		amount = v
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return v
+		}
+		amount = parsed
+	}
+
+	return formatNumber(amount / math.Pow(10, float64(decimalPlaces)))
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatPercent formats a ratio as a percentage
+func formatPercent(ratio float64) string {
+	return strconv.FormatFloat(ratio*100, 'f', 2, 64) + "%"
+}
+
+func marketName(market *proto.Market) string {
+	if market == nil || market.TradableInstrument == nil || market.TradableInstrument.Instrument == nil {
+		return ""
+	}
+	return market.TradableInstrument.Instrument.Name
+}
+
+func marketCode(market *proto.Market) string {
+	if market == nil || market.TradableInstrument == nil || market.TradableInstrument.Instrument == nil {
+		return ""
+	}
+	return market.TradableInstrument.Instrument.Code
+}
+
+// formatAsset formats an amount of asset, rounded to the asset decimals and
+// followed by its symbol
+func formatAsset(value float64, asset *proto.Asset) string {
+	scale := math.Pow(10, float64(asset.Decimals))
+	return formatNumber(math.Round(value*scale)/scale) + " " + asset.Symbol
+}
+
+// shortPartyID shortens a party public key to its first and last characters
+func shortPartyID(partyID string) string {
+	if len(partyID) <= 12 {
+		return partyID
+	}
+	return partyID[:6] + "…" + partyID[len(partyID)-4:]
+}
+
+// explorerLink returns the block explorer URL of an object, for example
+// explorerLink "parties" .Order.PartyId
+func explorerLink(kind string, id string) string {
+	return explorerURL + "/" + kind + "/" + id
+}
+
+// governanceLink returns the governance page URL of a proposal
+func governanceLink(proposalID string) string {
+	return governanceURL + "/" + proposalID
+}
@@ -0,0 +1,207 @@
+package state
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket       = []byte("meta")
+	parametersBucket = []byte("parameters")
+	auctionsBucket   = []byte("auctions")
+	sentBucket       = []byte("sent")
+
+	uptimeKey         = []byte("uptime")
+	ethereumConfigKey = []byte("ethereumConfig")
+	lastBlockKey      = []byte("lastBlock")
+)
+
+// BoltStore is a StateStore backed by an embedded BoltDB database. Every
+// write is a transaction, so it is either fully applied or not at all
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens, or creates, the database at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open state database "+path)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{metaBucket, parametersBucket, auctionsBucket, sentBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "could not initialize state database")
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (store *BoltStore) get(bucket []byte, key []byte) ([]byte, error) {
+	var value []byte
+	err := store.db.View(func(tx *bolt.Tx) error {
+		if stored := tx.Bucket(bucket).Get(key); stored != nil {
+			value = append([]byte(nil), stored...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (store *BoltStore) put(bucket []byte, key []byte, value []byte) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, value)
+	})
+}
+
+// Uptime returns the last known network start time
+func (store *BoltStore) Uptime() (string, bool, error) {
+	value, err := store.get(metaBucket, uptimeKey)
+	if err != nil {
+		return "", false, errors.Wrap(err, "could not read uptime")
+	}
+	return string(value), value != nil, nil
+}
+
+// SetUptime stores the network start time
+func (store *BoltStore) SetUptime(uptime string) error {
+	return errors.Wrap(store.put(metaBucket, uptimeKey, []byte(uptime)), "could not write uptime")
+}
+
+// EthereumConfig returns the last known Ethereum configuration
+func (store *BoltStore) EthereumConfig() (*proto.NetworkParameter, error) {
+	value, err := store.get(metaBucket, ethereumConfigKey)
+	if err != nil || value == nil {
+		return nil, errors.Wrap(err, "could not read Ethereum config")
+	}
+
+	var config proto.NetworkParameter
+	err = json.Unmarshal(value, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid stored Ethereum config")
+	}
+	return &config, nil
+}
+
+// SetEthereumConfig stores the Ethereum configuration
+func (store *BoltStore) SetEthereumConfig(config *proto.NetworkParameter) error {
+	value, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(store.put(metaBucket, ethereumConfigKey, value), "could not write Ethereum config")
+}
+
+// NetworkParameters returns the last known network parameter values
+func (store *BoltStore) NetworkParameters() (map[string]string, error) {
+	parameters := make(map[string]string)
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(parametersBucket).ForEach(func(key []byte, value []byte) error {
+			parameters[string(key)] = string(value)
+			return nil
+		})
+	})
+	return parameters, errors.Wrap(err, "could not read network parameters")
+}
+
+// SetNetworkParameter stores the value of a network parameter
+func (store *BoltStore) SetNetworkParameter(key string, value string) error {
+	return errors.Wrap(store.put(parametersBucket, []byte(key), []byte(value)), "could not write network parameter "+key)
+}
+
+// Auctions returns the active auctions
+func (store *BoltStore) Auctions() ([]Auction, error) {
+	var auctions []Auction
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(auctionsBucket).ForEach(func(key []byte, value []byte) error {
+			var auction Auction
+			if err := json.Unmarshal(value, &auction); err != nil {
+				return err
+			}
+			auctions = append(auctions, auction)
+			return nil
+		})
+	})
+	return auctions, errors.Wrap(err, "could not read auctions")
+}
+
+// SetAuction stores an active auction
+func (store *BoltStore) SetAuction(auction Auction) error {
+	value, err := json.Marshal(auction)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(store.put(auctionsBucket, []byte(auction.MarketID), value), "could not write auction")
+}
+
+// DeleteAuction forgets the auction of a market
+func (store *BoltStore) DeleteAuction(marketID string) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(auctionsBucket).Delete([]byte(marketID))
+	})
+	return errors.Wrap(err, "could not delete auction")
+}
+
+// LastBlock returns the last processed block
+func (store *BoltStore) LastBlock() (string, error) {
+	value, err := store.get(metaBucket, lastBlockKey)
+	return string(value), errors.Wrap(err, "could not read last block")
+}
+
+// SetLastBlock stores the last processed block
+func (store *BoltStore) SetLastBlock(block string) error {
+	return errors.Wrap(store.put(metaBucket, lastBlockKey, []byte(block)), "could not write last block")
+}
+
+// Sent returns when a message with the given hash was sent
+func (store *BoltStore) Sent(hash string) (time.Time, bool, error) {
+	value, err := store.get(sentBucket, []byte(hash))
+	if err != nil {
+		return time.Time{}, false, errors.Wrap(err, "could not read sent messages")
+	}
+	if len(value) != 8 {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(value))), true, nil
+}
+
+// SetSent records that a message with the given hash was sent
+func (store *BoltStore) SetSent(hash string, at time.Time) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(at.UnixNano()))
+	return errors.Wrap(store.put(sentBucket, []byte(hash), value), "could not write sent message")
+}
+
+// PruneSent forgets the messages sent before the given time
+func (store *BoltStore) PruneSent(before time.Time) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(sentBucket).Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			if len(value) != 8 || time.Unix(0, int64(binary.BigEndian.Uint64(value))).Before(before) {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "could not prune sent messages")
+}
+
+// Close closes the database
+func (store *BoltStore) Close() error {
+	return store.db.Close()
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+)
+
+// Files used to keep the state before the state store existed
+const (
+	legacyUptimeFile         = "uptime.conf"
+	legacyEthereumConfigFile = "ethereum.conf"
+)
+
+// MigrateFiles imports the state files found in dir into the store. Imported
+// files are renamed with the .migrated extension so they are imported once
+func MigrateFiles(store StateStore, dir string) error {
+	uptimePath := filepath.Join(dir, legacyUptimeFile)
+	uptime, err := ioutil.ReadFile(uptimePath)
+	if err == nil {
+		err = store.SetUptime(string(uptime))
+		if err != nil {
+			return err
+		}
+		err = markMigrated(uptimePath)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "could not read "+uptimePath)
+	}
+
+	ethereumPath := filepath.Join(dir, legacyEthereumConfigFile)
+	content, err := ioutil.ReadFile(ethereumPath)
+	if err == nil {
+		var config proto.NetworkParameter
+		err = json.Unmarshal(content, &config)
+		if err != nil {
+			return errors.Wrap(err, "invalid Ethereum config in "+ethereumPath)
+		}
+		err = store.SetEthereumConfig(&config)
+		if err != nil {
+			return err
+		}
+		err = markMigrated(ethereumPath)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "could not read "+ethereumPath)
+	}
+
+	return nil
+}
+
+func markMigrated(path string) error {
+	log.Println("Migrated " + path + " to the state store")
+	return errors.Wrap(os.Rename(path, path+".migrated"), "could not rename "+path)
+}
@@ -0,0 +1,47 @@
+package state
+
+import (
+	"time"
+
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+)
+
+// Auction is an auction the bot has announced and not seen end yet
+type Auction struct {
+	MarketID string               `json:"marketId"`
+	Trigger  proto.AuctionTrigger `json:"trigger"`
+	Start    int64                `json:"start"`
+	End      int64                `json:"end"`
+}
+
+// StateStore persists the state the bot needs across restarts
+type StateStore interface {
+	// Uptime returns the last known network start time, ok is false when none is stored
+	Uptime() (uptime string, ok bool, err error)
+	SetUptime(uptime string) error
+
+	// EthereumConfig returns the last known Ethereum configuration, nil when none is stored
+	EthereumConfig() (*proto.NetworkParameter, error)
+	SetEthereumConfig(config *proto.NetworkParameter) error
+
+	// NetworkParameters returns the last known network parameter values by key
+	NetworkParameters() (map[string]string, error)
+	SetNetworkParameter(key string, value string) error
+
+	// Auctions returns the active auctions
+	Auctions() ([]Auction, error)
+	SetAuction(auction Auction) error
+	DeleteAuction(marketID string) error
+
+	// LastBlock returns the last processed block, empty when none is stored
+	LastBlock() (string, error)
+	SetLastBlock(block string) error
+
+	// Sent returns when a message with the given hash was sent, ok is false if it never was
+	Sent(hash string) (at time.Time, ok bool, err error)
+	SetSent(hash string, at time.Time) error
+	// PruneSent forgets the messages sent before the given time
+	PruneSent(before time.Time) error
+
+	Close() error
+}
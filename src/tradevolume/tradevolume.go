@@ -0,0 +1,150 @@
+package tradevolume
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxSamples bounds the number of trades kept per market and window
+const maxSamples = 100000
+
+type sample struct {
+	at       time.Time
+	notional float64
+}
+
+// window keeps the trades of a market over a rolling duration. The notionals
+// are also kept sorted, so percentiles are read without sorting
+type window struct {
+	length  time.Duration
+	samples []sample
+	sorted  []float64
+	total   float64
+}
+
+func (w *window) add(s sample) {
+	w.samples = append(w.samples, s)
+	i := sort.SearchFloat64s(w.sorted, s.notional)
+	w.sorted = append(w.sorted, 0)
+	copy(w.sorted[i+1:], w.sorted[i:])
+	w.sorted[i] = s.notional
+	w.total += s.notional
+	if len(w.samples) > maxSamples {
+		w.drop(len(w.samples) - maxSamples)
+	}
+}
+
+// prune drops the trades older than the window
+func (w *window) prune(now time.Time) {
+	start := now.Add(-w.length)
+	i := sort.Search(len(w.samples), func(i int) bool { return !w.samples[i].at.Before(start) })
+	w.drop(i)
+}
+
+// drop forgets the n oldest trades
+func (w *window) drop(n int) {
+	if n <= 0 {
+		return
+	}
+	for _, s := range w.samples[:n] {
+		i := sort.SearchFloat64s(w.sorted, s.notional)
+		w.sorted = append(w.sorted[:i], w.sorted[i+1:]...)
+		w.total -= s.notional
+	}
+	// the backing array is released by the next append reallocating it
+	w.samples = w.samples[n:]
+	if len(w.samples) == 0 {
+		w.total = 0
+	}
+}
+
+// Tracker keeps the notional of the trades of every market over several
+// rolling windows, for example 1h and 24h
+type Tracker struct {
+	windows []time.Duration
+	mu      sync.Mutex
+	markets map[string]map[time.Duration]*window
+}
+
+// NewTracker creates a tracker keeping the trades of the last windows
+func NewTracker(windows ...time.Duration) *Tracker {
+	return &Tracker{
+		windows: windows,
+		markets: make(map[string]map[time.Duration]*window),
+	}
+}
+
+// Windows returns the rolling windows kept by the tracker
+func (tracker *Tracker) Windows() []time.Duration {
+	return tracker.windows
+}
+
+// Add records a trade in every window
+func (tracker *Tracker) Add(marketID string, at time.Time, notional float64) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	windows, ok := tracker.markets[marketID]
+	if !ok {
+		windows = make(map[time.Duration]*window)
+		for _, length := range tracker.windows {
+			windows[length] = &window{length: length}
+		}
+		tracker.markets[marketID] = windows
+	}
+	for _, w := range windows {
+		w.add(sample{at: at, notional: notional})
+	}
+}
+
+// Total returns the traded notional of a market within a window and the number of trades
+func (tracker *Tracker) Total(marketID string, length time.Duration, now time.Time) (float64, int) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	w := tracker.window(marketID, length, now)
+	if w == nil {
+		return 0, 0
+	}
+	return w.total, len(w.samples)
+}
+
+// Average returns the average trade notional of a market within a window and the number of trades
+func (tracker *Tracker) Average(marketID string, length time.Duration, now time.Time) (float64, int) {
+	total, count := tracker.Total(marketID, length, now)
+	if count == 0 {
+		return 0, 0
+	}
+	return total / float64(count), count
+}
+
+// Percentile returns the p-th percentile (0-100) of the trade notionals of a
+// market within a window and the number of trades
+func (tracker *Tracker) Percentile(marketID string, p float64, length time.Duration, now time.Time) (float64, int) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	w := tracker.window(marketID, length, now)
+	if w == nil || len(w.sorted) == 0 {
+		return 0, 0
+	}
+	index := int(p / 100 * float64(len(w.sorted)-1))
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(w.sorted) {
+		index = len(w.sorted) - 1
+	}
+	return w.sorted[index], len(w.sorted)
+}
+
+// window returns a pruned window of a market, nil when unknown. The lock must be held
+func (tracker *Tracker) window(marketID string, length time.Duration, now time.Time) *window {
+	w, ok := tracker.markets[marketID][length]
+	if !ok {
+		return nil
+	}
+	w.prune(now)
+	return w
+}
@@ -0,0 +1,136 @@
+package tradevolume
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type trade struct {
+	after    time.Duration
+	notional float64
+}
+
+func newTestTracker(trades []trade) *Tracker {
+	tracker := NewTracker(time.Hour, 24*time.Hour)
+	for _, t := range trades {
+		tracker.Add("market", start.Add(t.after), t.notional)
+	}
+	return tracker
+}
+
+func TestTotalAndAverage(t *testing.T) {
+	trades := []trade{
+		{0, 100},
+		{2 * time.Hour, 200},
+		{23 * time.Hour, 300},
+		{24*time.Hour + 30*time.Minute, 400},
+	}
+	tests := []struct {
+		name        string
+		window      time.Duration
+		now         time.Duration
+		wantTotal   float64
+		wantCount   int
+		wantAverage float64
+	}{
+		{"24h keeps the whole day", 24 * time.Hour, 24 * time.Hour, 1000, 4, 250},
+		{"24h prunes older trades", 24 * time.Hour, 25*time.Hour + time.Minute, 900, 3, 300},
+		{"1h keeps the last hour", time.Hour, 24*time.Hour + 45*time.Minute, 400, 1, 400},
+		{"window start is inclusive", time.Hour, 25*time.Hour + 30*time.Minute, 400, 1, 400},
+		{"everything expired", time.Hour, 48 * time.Hour, 0, 0, 0},
+		{"unknown window", 2 * time.Hour, 24 * time.Hour, 0, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := newTestTracker(trades)
+			now := start.Add(test.now)
+			total, count := tracker.Total("market", test.window, now)
+			if total != test.wantTotal || count != test.wantCount {
+				t.Errorf("Total = %v, %d, want %v, %d", total, count, test.wantTotal, test.wantCount)
+			}
+			average, count := tracker.Average("market", test.window, now)
+			if average != test.wantAverage || count != test.wantCount {
+				t.Errorf("Average = %v, %d, want %v, %d", average, count, test.wantAverage, test.wantCount)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	trades := []trade{
+		{0, 50},
+		{time.Minute, 10},
+		{2 * time.Minute, 30},
+		{3 * time.Minute, 30},
+		{4 * time.Minute, 20},
+		{5 * time.Minute, 40},
+	}
+	tests := []struct {
+		name      string
+		p         float64
+		now       time.Duration
+		want      float64
+		wantCount int
+	}{
+		{"minimum", 0, 10 * time.Minute, 10, 6},
+		{"median with duplicates", 50, 10 * time.Minute, 30, 6},
+		{"rounds the index down", 90, 10 * time.Minute, 40, 6},
+		{"maximum", 100, 10 * time.Minute, 50, 6},
+		{"above 100 is the maximum", 150, 10 * time.Minute, 50, 6},
+		{"below 0 is the minimum", -10, 10 * time.Minute, 10, 6},
+		{"pruned trades leave the sorted notionals", 100, time.Hour + 30*time.Second, 40, 5},
+		{"pruned duplicate is removed once", 0, time.Hour + 2*time.Minute + 30*time.Second, 20, 3},
+		{"empty window", 50, 2 * time.Hour, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := newTestTracker(trades)
+			got, count := tracker.Percentile("market", test.p, time.Hour, start.Add(test.now))
+			if got != test.want || count != test.wantCount {
+				t.Errorf("Percentile(%v) = %v, %d, want %v, %d", test.p, got, count, test.want, test.wantCount)
+			}
+		})
+	}
+}
+
+func TestUnknownMarket(t *testing.T) {
+	tracker := NewTracker(time.Hour)
+	if total, count := tracker.Total("market", time.Hour, start); total != 0 || count != 0 {
+		t.Errorf("Total = %v, %d, want 0, 0", total, count)
+	}
+	if p, count := tracker.Percentile("market", 50, time.Hour, start); p != 0 || count != 0 {
+		t.Errorf("Percentile = %v, %d, want 0, 0", p, count)
+	}
+}
+
+func TestMaxSamples(t *testing.T) {
+	tracker := NewTracker(time.Hour)
+	extra := 10
+	for i := 0; i < maxSamples+extra; i++ {
+		tracker.Add("market", start, float64(i%100))
+	}
+
+	// the oldest trades, notionals 0 to 9, are dropped
+	var want float64
+	for i := extra; i < maxSamples+extra; i++ {
+		want += float64(i % 100)
+	}
+	total, count := tracker.Total("market", time.Hour, start)
+	if count != maxSamples || total != want {
+		t.Errorf("Total = %v, %d, want %v, %d", total, count, want, maxSamples)
+	}
+	w := tracker.markets["market"][time.Hour]
+	if len(w.sorted) != maxSamples {
+		t.Fatalf("%d sorted notionals, want %d", len(w.sorted), maxSamples)
+	}
+	for i := 1; i < len(w.sorted); i++ {
+		if w.sorted[i-1] > w.sorted[i] {
+			t.Fatalf("notionals not sorted at %d: %v > %v", i, w.sorted[i-1], w.sorted[i])
+		}
+	}
+	if min, _ := tracker.Percentile("market", 0, time.Hour, start); min != 0 {
+		t.Errorf("minimum = %v, want 0, the later trades of notional 0 are kept", min)
+	}
+}
@@ -0,0 +1,94 @@
+package vegaclient
+
+import (
+	"log"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// Conn is a gRPC connection to a Vega node that can fail over to another node
+// of the pool without invalidating the clients built on top of it
+type Conn struct {
+	pool *NodePool
+	mu   sync.RWMutex
+	url  string
+	conn *grpc.ClientConn
+}
+
+// Dial connects to the healthy node of the pool with the highest block height.
+// When no node is healthy the first node of the pool is used
+func Dial(pool *NodePool) (*Conn, error) {
+	url, err := pool.Best()
+	if err != nil {
+		log.Printf("%s. Falling back to %s\n", err, pool.urls[0])
+		url = pool.urls[0]
+	}
+
+	conn := &Conn{pool: pool}
+	err = conn.dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Redial replaces the current connection with one to the next healthy node
+func (conn *Conn) Redial() error {
+	url, err := conn.pool.Next(conn.URL())
+	if err != nil {
+		return err
+	}
+
+	return conn.dial(url)
+}
+
+// URL returns the endpoint of the node currently in use
+func (conn *Conn) URL() string {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	return conn.url
+}
+
+// Invoke performs a unary RPC on the current connection
+func (conn *Conn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	return conn.current().Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream begins a streaming RPC on the current connection
+func (conn *Conn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return conn.current().NewStream(ctx, desc, method, opts...)
+}
+
+// Close closes the current connection
+func (conn *Conn) Close() error {
+	return conn.current().Close()
+}
+
+func (conn *Conn) dial(url string) error {
+	grpcConn, err := grpc.Dial(url, grpc.WithInsecure(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(256<<22)))
+	if err != nil {
+		return err
+	}
+
+	conn.mu.Lock()
+	previous := conn.conn
+	conn.conn = grpcConn
+	conn.url = url
+	conn.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	log.Println("Dialing Vega node " + url)
+
+	return nil
+}
+
+func (conn *Conn) current() *grpc.ClientConn {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	return conn.conn
+}
@@ -0,0 +1,134 @@
+package vegaclient
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+)
+
+const initialReconnectBackoff = time.Second
+
+var (
+	streamReconnections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vegabot_eventbus_reconnections_total",
+		Help: "Number of times the event bus stream has been reopened",
+	})
+	streamDowntime = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vegabot_eventbus_downtime_seconds_total",
+		Help: "Total time the event bus stream has been down",
+	})
+)
+
+// EventBusConsumer observes the Vega event bus and reopens the stream when it fails
+type EventBusConsumer struct {
+	conn        *Conn
+	dataClient  api.TradingDataServiceClient
+	request     api.ObserveEventBusRequest
+	maxBackoff  time.Duration
+	onReconnect func()
+}
+
+// NewEventBusConsumer creates a supervised event bus consumer
+func NewEventBusConsumer(conn *Conn, eventTypes []proto.BusEventType, batchSize int64, maxBackoff time.Duration) *EventBusConsumer {
+	return &EventBusConsumer{
+		conn:       conn,
+		dataClient: api.NewTradingDataServiceClient(conn),
+		request:    api.ObserveEventBusRequest{Type: eventTypes, BatchSize: batchSize},
+		maxBackoff: maxBackoff,
+	}
+}
+
+// OnReconnect sets a function called every time the stream is restored after an interruption
+func (consumer *EventBusConsumer) OnReconnect(fn func()) {
+	consumer.onReconnect = fn
+}
+
+// Run consumes events until the context is cancelled. The stream is reopened
+// with exponential backoff every time it is closed or fails
+func (consumer *EventBusConsumer) Run(ctx context.Context, handle func(event *proto.BusEvent)) error {
+	backoff := initialReconnectBackoff
+	var downSince time.Time
+
+	for {
+		err := consumer.observe(ctx, handle, func() {
+			if !downSince.IsZero() {
+				downtime := time.Since(downSince)
+				streamDowntime.Add(downtime.Seconds())
+				log.Printf("Event bus stream restored after %s\n", downtime)
+				downSince = time.Time{}
+				if consumer.onReconnect != nil {
+					consumer.onReconnect()
+				}
+			}
+			backoff = initialReconnectBackoff
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if downSince.IsZero() {
+			downSince = time.Now()
+		}
+		log.Printf("Event bus stream interrupted: %s. Reconnecting in %s\n", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = backoff * 2
+		if backoff > consumer.maxBackoff {
+			backoff = consumer.maxBackoff
+		}
+
+		streamReconnections.Inc()
+		err = consumer.conn.Redial()
+		if err != nil {
+			log.Printf("Could not fail over to another Vega node: %s\n", err)
+		}
+	}
+}
+
+func (consumer *EventBusConsumer) observe(ctx context.Context, handle func(event *proto.BusEvent), connected func()) error {
+	events, err := consumer.dataClient.ObserveEventBus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not open event bus stream")
+	}
+
+	// When the batchSize is too small -> "rpc error: code = Unknown desc = EOF"
+	log.Printf("Listening to event types: %v\n", consumer.request.Type)
+	err = events.Send(&consumer.request)
+	if err != nil {
+		return errors.Wrap(err, "could not send event bus request")
+	}
+	events.CloseSend()
+
+	// the stream is only considered restored once the node delivers events
+	received := false
+	for {
+		resp, err := events.Recv()
+		if err == io.EOF {
+			return errors.New("stream closed by the node")
+		}
+		if err != nil {
+			return err
+		}
+		if !received {
+			received = true
+			log.Printf("Event bus stream session served by Vega node %s\n", consumer.conn.URL())
+			connected()
+		}
+
+		for _, event := range resp.Events {
+			handle(event)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package vegaclient
+
+import (
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto"
+	"github.com/vegaprotocol/api-clients/go/generated/code.vegaprotocol.io/vega/proto/api"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const probeTimeout = 5 * time.Second
+
+// NodePool is an ordered list of Vega data nodes
+type NodePool struct {
+	urls []string
+}
+
+// NewNodePool creates a pool from an ordered list of node gRPC endpoints
+func NewNodePool(urls []string) (*NodePool, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no Vega node configured")
+	}
+
+	return &NodePool{urls: urls}, nil
+}
+
+// Best returns the healthy node with the highest block height
+func (pool *NodePool) Best() (string, error) {
+	best := ""
+	var bestHeight uint64
+	for _, url := range pool.urls {
+		height, err := probe(url)
+		if err != nil {
+			log.Printf("Vega node %s is not healthy: %s\n", url, err)
+			continue
+		}
+		log.Printf("Vega node %s is at block height %d\n", url, height)
+		if best == "" || height > bestHeight {
+			best = url
+			bestHeight = height
+		}
+	}
+
+	if best == "" {
+		return "", errors.New("no healthy Vega node available")
+	}
+
+	return best, nil
+}
+
+// Next returns the first healthy node following current in the list. current
+// is tried last
+func (pool *NodePool) Next(current string) (string, error) {
+	start := 0
+	for i, url := range pool.urls {
+		if url == current {
+			start = i + 1
+			break
+		}
+	}
+
+	for i := 0; i < len(pool.urls); i++ {
+		url := pool.urls[(start+i)%len(pool.urls)]
+		_, err := probe(url)
+		if err != nil {
+			log.Printf("Vega node %s is not healthy: %s\n", url, err)
+			continue
+		}
+		return url, nil
+	}
+
+	return "", errors.New("no healthy Vega node available")
+}
+
+// probe returns the block height of a node, or an error when the node is not
+// reachable or not connected to the chain
+func probe(url string) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, url, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	dataClient := api.NewTradingDataServiceClient(conn)
+	stats, err := dataClient.Statistics(ctx, &api.StatisticsRequest{})
+	if err != nil {
+		return 0, err
+	}
+
+	if stats.Statistics.Status != proto.ChainStatus_CHAIN_STATUS_CONNECTED {
+		return 0, errors.New("chain status is " + stats.Statistics.Status.String())
+	}
+
+	return stats.Statistics.BlockHeight, nil
+}